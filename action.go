@@ -1,5 +1,13 @@
 package clir
 
+import "context"
+
 // Action represents a function that gets calls when the command is called by
 // the user
 type Action func() error
+
+// ActionContext represents a function that gets called when the command is
+// called by the user. The context is cancelled when the application is
+// interrupted, if signal handling is enabled, or when the context given to
+// Cli.RunContext is done
+type ActionContext func(ctx context.Context) error
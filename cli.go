@@ -1,8 +1,12 @@
 package clir
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+
+	"github.com/leaanthony/clir/internal/hooks"
 )
 
 // Cli - The main application object.
@@ -14,6 +18,19 @@ type Cli struct {
 	postRunCommand func(*Cli) error
 	bannerFunction func(*Cli) string
 	errorHandler   func(string, error) error
+	envPrefix      string
+	config         *configFile
+	handleSignals  bool
+	helpRenderer   HelpRenderer
+	output         io.Writer
+	errOutput      io.Writer
+	savedValues    map[interface{}]*savedValue
+	executed       *Command
+	prefixMatching bool
+	verbosity      *verbosity
+	// flagSources records where accumulating flag values were set from
+	// during a run
+	flagSources map[accumulatingValue]flagSource
 }
 
 // Version - Get the Application version string.
@@ -39,20 +56,108 @@ func (c *Cli) SetBannerFunction(fn func(*Cli) string) {
 
 // SetErrorFunction - Set custom error message when undefined
 // flags are used by the user. First argument is a string containing
-// the command path used. Second argument is the undefined flag error.
+// the command path used. Second argument is the error, which is one of
+// the typed errors such as *UnknownFlagError. Wrap it, EG: using `%w`,
+// so that RunAndExit exits with the code for an invalid command line.
 func (c *Cli) SetErrorFunction(fn func(string, error) error) {
 	c.errorHandler = fn
 }
 
+// SetEnvPrefix - Binds every flag to an environment variable named after
+// the prefix, the command path and the flag name, EG: with the prefix
+// "MYAPP", the flag "region" of the "deploy" command is bound to
+// `MYAPP_DEPLOY_REGION`. Flags explicitly bound using the `env` tag or
+// `EnvVar` method keep their given name.
+func (c *Cli) SetEnvPrefix(prefix string) *Cli {
+	c.envPrefix = prefix
+	return c
+}
+
+// ConfigFile - Loads flag values from a JSON, TOML, YAML or INI config file.
+// If flagName is not blank, a persistent flag with that name is added to
+// allow the config file to be given on the command line. Otherwise, the
+// first of the given search paths that exists is used, EG:
+// `cli.ConfigFile("config", clir.DefaultConfigPaths("myapp")...)`.
+// Top level keys in the config file are the flags of the root command and
+// nested keys are the flags of subcommands, EG: `deploy.region`.
+// Values given on the command line or through environment variables take
+// precedence over the config file, which takes precedence over defaults.
+func (c *Cli) ConfigFile(flagName string, searchPaths ...string) *Cli {
+	c.config = &configFile{
+		flagName:    flagName,
+		searchPaths: searchPaths,
+	}
+	if flagName != "" {
+		var path string
+		c.PersistentFlags().StringFlag(flagName, "The config file to use", &path)
+	}
+	return c
+}
+
+// EnablePrefixMatching - Allows subcommands to be run by a prefix of their
+// name or one of their aliases, EG: `app dep` runs `app deploy`, as long as
+// the prefix matches only one subcommand. Hidden commands must be given in
+// full.
+func (c *Cli) EnablePrefixMatching() *Cli {
+	c.prefixMatching = true
+	return c
+}
+
 // AddCommand - Adds a command to the application.
 func (c *Cli) AddCommand(command *Command) {
 	c.rootCommand.AddCommand(command)
 }
 
+// SetHelpRenderer - Sets the renderer used to print the help of every
+// command, EG: `cli.SetHelpRenderer(clir.HelpTemplate(text))`. Commands
+// may override it using Command.SetHelpRenderer.
+func (c *Cli) SetHelpRenderer(renderer HelpRenderer) *Cli {
+	c.helpRenderer = renderer
+	return c
+}
+
+// SetOutput - Sets the writer that the banner, help and other regular
+// output is printed to. Defaults to os.Stdout.
+func (c *Cli) SetOutput(w io.Writer) *Cli {
+	c.output = w
+	return c
+}
+
+// SetErrOutput - Sets the writer that errors are printed to.
+// Defaults to os.Stderr.
+func (c *Cli) SetErrOutput(w io.Writer) *Cli {
+	c.errOutput = w
+	return c
+}
+
+// Output - Returns the writer that regular output is printed to. Actions
+// may print to it so their output can be captured, EG: in tests.
+func (c *Cli) Output() io.Writer {
+	if c.output == nil {
+		return os.Stdout
+	}
+	return c.output
+}
+
+// ErrOutput - Returns the writer that errors are printed to
+func (c *Cli) ErrOutput() io.Writer {
+	if c.errOutput == nil {
+		return os.Stderr
+	}
+	return c.errOutput
+}
+
+// ExecutedCommand - Returns the command that was run, or had its help
+// printed, by the most recent run of the application. It returns nil if
+// the application has not been run or failed before a command was found.
+func (c *Cli) ExecutedCommand() *Command {
+	return c.executed
+}
+
 // PrintBanner - Prints the application banner!
 func (c *Cli) PrintBanner() {
-	fmt.Println(c.bannerFunction(c))
-	fmt.Println("")
+	fmt.Fprintln(c.Output(), c.bannerFunction(c))
+	fmt.Fprintln(c.Output(), "")
 }
 
 // PrintHelp - Prints the application's help.
@@ -62,20 +167,68 @@ func (c *Cli) PrintHelp() {
 
 // Run - Runs the application with the given arguments.
 func (c *Cli) Run(args ...string) error {
-	if c.preRunCommand != nil {
+	return c.RunContext(context.Background(), args...)
+}
+
+// RunAndExit - Runs the application with the given arguments and exits
+// with the code returned by ExitCode. Any error is printed to ErrOutput.
+// Invalid command lines exit with code 2, allowing scripts to tell them
+// apart from actions that failed.
+func (c *Cli) RunAndExit(args ...string) {
+	err := c.Run(args...)
+	if err != nil {
+		fmt.Fprintln(c.ErrOutput(), err)
+	}
+	exit(ExitCode(err))
+}
+
+// RunContext - Runs the application with the given context and arguments.
+// The context is passed to actions defined with ActionContext and to
+// functions given to NewSubCommandFunction that accept a context.
+func (c *Cli) RunContext(ctx context.Context, args ...string) error {
+	if len(args) == 0 {
+		args = os.Args[1:]
+	}
+	return c.runArgs(ctx, args)
+}
+
+func init() {
+	hooks.RunArgs = func(ctx context.Context, cli interface{}, args []string) error {
+		return cli.(*Cli).runArgs(ctx, args)
+	}
+}
+
+// runArgs runs the application with exactly the given arguments, which may
+// be empty
+func (c *Cli) runArgs(ctx context.Context, args []string) error {
+	c.saveFlags()
+	c.executed = nil
+	c.flagSources = nil
+	if c.handleSignals {
+		var stop func()
+		ctx, stop = notifyContext(ctx)
+		defer stop()
+	}
+	internal := c.isInternalCommand(args)
+	if c.preRunCommand != nil && !internal {
 		err := c.preRunCommand(c)
 		if err != nil {
 			return err
 		}
 	}
-	if len(args) == 0 {
-		args = os.Args[1:]
+	if c.config != nil && internal {
+		// Internal commands don't use the config file
+		c.config.path, c.config.values = "", nil
+	} else if c.config != nil {
+		if err := c.config.load(c.rootCommand, args); err != nil {
+			return err
+		}
 	}
-	if err := c.rootCommand.run(args); err != nil {
+	if err := c.rootCommand.runContext(ctx, args); err != nil {
 		return err
 	}
 
-	if c.postRunCommand != nil {
+	if c.postRunCommand != nil && !internal {
 		err := c.postRunCommand(c)
 		if err != nil {
 			return err
@@ -85,6 +238,28 @@ func (c *Cli) Run(args ...string) error {
 	return nil
 }
 
+// isInternalCommand returns true if the arguments run an internal command,
+// such as `__complete`, which is called by the completion scripts. The
+// command may follow the root command's flags, EG: `app -v __complete`.
+func (c *Cli) isInternalCommand(args []string) bool {
+	root := c.rootCommand
+	root.mergePersistentFlags()
+	end := root.leadingFlagsEnd(args)
+	if end == len(args) {
+		return false
+	}
+	command := root.findSubCommand(args[end])
+	return command != nil && command.internal
+}
+
+// HandleSignals - Cancels the context passed to actions when the
+// application receives SIGINT or SIGTERM, allowing them to clean up.
+// If a second signal is received, the application exits immediately.
+func (c *Cli) HandleSignals() *Cli {
+	c.handleSignals = true
+	return c
+}
+
 // DefaultCommand - Sets the given command as the command to run when
 // no other commands given.
 func (c *Cli) DefaultCommand(defaultCommand *Command) *Cli {
@@ -130,6 +305,37 @@ func (c *Cli) IntFlag(name, description string, variable *int) *Cli {
 	return c
 }
 
+// PersistentFlags - Returns the set of flags that are recognised by the
+// application and all of its subcommands.
+func (c *Cli) PersistentFlags() *Command {
+	return c.rootCommand.PersistentFlags()
+}
+
+// ShortFlag - Adds a single character alias for the named root command flag.
+func (c *Cli) ShortFlag(name, short string) *Cli {
+	c.rootCommand.ShortFlag(name, short)
+	return c
+}
+
+// EnvVar - Binds the named root command flag to the given environment variable.
+func (c *Cli) EnvVar(name, envVar string) *Cli {
+	c.rootCommand.EnvVar(name, envVar)
+	return c
+}
+
+// Required - Marks the named root command flags as required.
+func (c *Cli) Required(names ...string) *Cli {
+	c.rootCommand.Required(names...)
+	return c
+}
+
+// HideFlags - Hides the named root command flags from the help text,
+// generated documentation, shell completion and suggestions.
+func (c *Cli) HideFlags(names ...string) *Cli {
+	c.rootCommand.HideFlags(names...)
+	return c
+}
+
 func (c *Cli) AddFlags(flags interface{}) *Cli {
 	c.rootCommand.AddFlags(flags)
 	return c
@@ -141,6 +347,13 @@ func (c *Cli) Action(callback Action) *Cli {
 	return c
 }
 
+// ActionContext - Define an action from this command that receives the
+// context the application is run with.
+func (c *Cli) ActionContext(callback ActionContext) *Cli {
+	c.rootCommand.ActionContext(callback)
+	return c
+}
+
 // LongDescription - Sets the long description for the command.
 func (c *Cli) LongDescription(longdescription string) *Cli {
 	c.rootCommand.LongDescription(longdescription)
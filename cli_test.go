@@ -1,8 +1,11 @@
 package clir
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -250,6 +253,27 @@ func TestCli_CommandOtherCLIArgs(t *testing.T) {
 
 }
 
+func TestCli_TerminatorOtherArgs(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	var verbose bool
+	c.BoolFlag("verbose", "Show more output", &verbose)
+
+	var other []string
+	c.Action(func() error {
+		other = c.OtherArgs()
+		return nil
+	})
+	if e := c.Run("file", "--verbose", "--", "-x", "--verbose"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !verbose {
+		t.Errorf("expected verbose to be set")
+	}
+	if strings.Join(other, " ") != "file -x --verbose" {
+		t.Errorf("expected args after the terminator to be other args, got %v", other)
+	}
+}
+
 type Person struct {
 	Name        string  `description:"The name of the person"`
 	Age         int     `description:"The age of the person"`
@@ -575,3 +599,567 @@ func TestCli_CommandAddFunctionUsesDefaults(t *testing.T) {
 		t.Errorf("expected no error, got %v", e)
 	}
 }
+
+type ShortFlags struct {
+	Verbose bool   `description:"Verbose output" short:"v"`
+	Force   bool   `description:"Force the operation" short:"f"`
+	Output  string `description:"The output file" short:"o"`
+}
+
+func TestCli_ShortFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		verbose bool
+		force   bool
+		output  string
+	}{
+		{"single", []string{"-v"}, true, false, ""},
+		{"long", []string{"--verbose", "--output", "file.txt"}, true, false, "file.txt"},
+		{"bundled", []string{"-vf"}, true, true, ""},
+		{"attached value", []string{"-ofile.txt"}, false, false, "file.txt"},
+		{"separate value", []string{"-o", "file.txt"}, false, false, "file.txt"},
+		{"bundled with value", []string{"-vfo", "file.txt"}, true, true, "file.txt"},
+		{"bundled with attached value", []string{"-vofile.txt"}, true, false, "file.txt"},
+		{"value looks like a flag", []string{"-o", "-v"}, false, false, "-v"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCli("test", "description", "0")
+			flags := &ShortFlags{}
+			c.AddFlags(flags)
+			c.Action(func() error { return nil })
+			if err := c.Run(tt.args...); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if flags.Verbose != tt.verbose {
+				t.Errorf("expected verbose to be %v, got %v", tt.verbose, flags.Verbose)
+			}
+			if flags.Force != tt.force {
+				t.Errorf("expected force to be %v, got %v", tt.force, flags.Force)
+			}
+			if flags.Output != tt.output {
+				t.Errorf("expected output to be %q, got %q", tt.output, flags.Output)
+			}
+		})
+	}
+}
+
+func TestCli_ShortFlagImperative(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	var name string
+	c.StringFlag("name", "The name", &name).ShortFlag("name", "n")
+	sub := c.NewSubCommand("sub", "sub description")
+	var count int
+	sub.IntFlag("count", "The count", &count).ShortFlag("count", "c")
+
+	if err := c.Run("-n", "Janet"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if name != "Janet" {
+		t.Errorf("expected name to be Janet, got %v", name)
+	}
+	if err := c.Run("sub", "-c3"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected count to be 3, got %v", count)
+	}
+	if err := c.Run("sub", "-x"); err == nil {
+		t.Errorf("expected error for unknown short flag")
+	}
+}
+
+func TestCli_ShortFlagUndefined(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Errorf("expected panic")
+		}
+		if r.(string) != "ShortFlag() requires flag 'name' to be defined first" {
+			t.Errorf("unexpected panic message: %v", r)
+		}
+	}()
+	c.ShortFlag("name", "n")
+}
+
+type GlobalFlags struct {
+	Verbose bool   `description:"Verbose output" short:"v"`
+	Config  string `description:"The config file"`
+}
+
+func TestCli_PersistentFlags(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	db := c.NewSubCommand("db", "database commands")
+	migrate := db.NewSubCommand("migrate", "migrate the database")
+	var dryRun bool
+	migrate.BoolFlag("dry-run", "Don't change anything", &dryRun)
+
+	// Defined after the subcommands
+	globals := &GlobalFlags{}
+	c.PersistentFlags().AddFlags(globals)
+	var region string
+	db.PersistentFlags().StringFlag("region", "The region", &region)
+
+	ran := false
+	migrate.Action(func() error {
+		ran = true
+		return nil
+	})
+	e := c.Run("db", "migrate", "-v", "--config", "x.json", "--region", "eu", "--dry-run")
+	if e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !ran {
+		t.Errorf("expected migrate action to run")
+	}
+	if !globals.Verbose || globals.Config != "x.json" || region != "eu" || !dryRun {
+		t.Errorf("unexpected flag values: %+v, region=%v, dryRun=%v", globals, region, dryRun)
+	}
+
+	// Persistent flags are also recognised by the command that defines them
+	globals.Verbose = false
+	c.Action(func() error { return nil })
+	if e := c.Run("--verbose"); e != nil {
+		t.Errorf("expected no error, got %v", e)
+	}
+	if !globals.Verbose {
+		t.Errorf("expected verbose to be set on root command")
+	}
+
+	// But not by ancestors
+	if e := c.Run("--region", "us"); e == nil {
+		t.Errorf("expected error when using a descendant's persistent flag")
+	}
+}
+
+func TestCli_PersistentFlagsShadowed(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	var global, local string
+	c.PersistentFlags().StringFlag("name", "global name", &global)
+	sub := c.NewSubCommand("sub", "sub description")
+	sub.StringFlag("name", "local name", &local)
+	sub.Action(func() error { return nil })
+
+	if e := c.Run("sub", "--name", "local"); e != nil {
+		t.Errorf("expected no error, got %v", e)
+	}
+	if local != "local" || global != "" {
+		t.Errorf("expected local flag to take precedence, got global=%q local=%q", global, local)
+	}
+	flags, _ := sub.globalFlags()
+	if len(flags) != 0 {
+		t.Errorf("expected shadowed flag to be excluded from global flags, got %v", flags)
+	}
+}
+
+func TestCli_PersistentFlagsMisuse(t *testing.T) {
+	tests := map[string]func(c *Cli){
+		"Action":          func(c *Cli) { c.PersistentFlags().Action(func() error { return nil }) },
+		"ActionContext":   func(c *Cli) { c.PersistentFlags().ActionContext(nil) },
+		"NewSubCommand":   func(c *Cli) { c.PersistentFlags().NewSubCommand("deploy", "deploy the app") },
+		"AddCommand":      func(c *Cli) { c.PersistentFlags().AddCommand(NewCommand("deploy", "deploy the app")) },
+		"PersistentFlags": func(c *Cli) { c.PersistentFlags().PersistentFlags() },
+	}
+	for method, setup := range tests {
+		func() {
+			defer func() {
+				expected := method + "() cannot be used on a persistent flag set"
+				if r := recover(); r != expected {
+					t.Errorf("expected panic %q, got %v", expected, r)
+				}
+			}()
+			setup(NewCli("test", "description", "0"))
+		}()
+	}
+}
+
+func TestCli_FlagsBeforeSubCommand(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	var config string
+	c.StringFlag("config", "The config file", &config)
+	db := c.NewSubCommand("db", "database commands")
+	var verbose bool
+	db.BoolFlag("verbose", "Verbose output", &verbose).ShortFlag("verbose", "v")
+	migrate := db.NewSubCommand("migrate", "migrate the database")
+	var dryRun bool
+	migrate.BoolFlag("dry-run", "Don't change anything", &dryRun)
+
+	var other []string
+	migrate.Action(func() error {
+		other = migrate.OtherArgs()
+		return nil
+	})
+	e := c.Run("--config", "x.json", "db", "-v", "migrate", "--dry-run", "extra")
+	if e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if config != "x.json" || !verbose || !dryRun {
+		t.Errorf("unexpected flag values: config=%q verbose=%v dryRun=%v", config, verbose, dryRun)
+	}
+	if len(other) != 1 || other[0] != "extra" {
+		t.Errorf("expected other args to be [extra], got %v", other)
+	}
+
+	// Subcommand names after a terminator are not treated as subcommands
+	var rootOther []string
+	c.Action(func() error {
+		rootOther = c.OtherArgs()
+		return nil
+	})
+	if e := c.Run("--config", "y.json", "--", "db"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if len(rootOther) != 1 || rootOther[0] != "db" {
+		t.Errorf("expected other args to be [db], got %v", rootOther)
+	}
+
+	// Unknown flags are still reported
+	if e := c.Run("--unknown", "db"); e == nil {
+		t.Errorf("expected error for unknown flag")
+	}
+}
+
+func TestCli_FlagsBeforeSubCommandShortFlags(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	var verbose, recursive bool
+	c.BoolFlag("verbose", "Verbose output", &verbose).ShortFlag("verbose", "v")
+	c.BoolFlag("recursive", "Recurse into directories", &recursive).ShortFlag("recursive", "r")
+	deploy := c.NewSubCommand("deploy", "deploy the app")
+	var region string
+	deploy.StringFlag("region", "The region to deploy to", &region).ShortFlag("region", "r")
+	deploy.Action(func() error { return nil })
+
+	// The subcommand's short flags are expanded by the subcommand
+	if e := c.Run("-v", "deploy", "-r", "eu"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !verbose || recursive || region != "eu" {
+		t.Errorf("unexpected flag values: verbose=%v recursive=%v region=%q", verbose, recursive, region)
+	}
+
+	c.ResetFlags()
+	if e := c.Run("-vr", "deploy", "-r", "us"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !verbose || !recursive || region != "us" {
+		t.Errorf("unexpected flag values: verbose=%v recursive=%v region=%q", verbose, recursive, region)
+	}
+}
+
+func TestCli_FlagsBeforeDefaultCommand(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	var verbose bool
+	c.BoolFlag("verbose", "Verbose output", &verbose)
+	deploy := c.NewSubCommand("deploy", "deploy the app")
+	var dryRun bool
+	deploy.BoolFlag("dry-run", "Don't change anything", &dryRun)
+	ran := 0
+	deploy.Action(func() error {
+		ran++
+		return nil
+	})
+	c.DefaultCommand(deploy)
+
+	if e := c.Run("--verbose"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !verbose || ran != 1 {
+		t.Errorf("expected default command to run with root flags, verbose=%v ran=%v", verbose, ran)
+	}
+
+	// Flags belonging to the default command are passed to it
+	if e := c.Run("--dry-run"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !dryRun || ran != 2 {
+		t.Errorf("expected default command to run with its flags, dryRun=%v ran=%v", dryRun, ran)
+	}
+}
+
+type RequiredFlags struct {
+	Name   string `pos:"1" description:"The name" required:"true"`
+	Count  int    `pos:"2" description:"The count"`
+	Region string `description:"The region" required:"true"`
+	Token  string `description:"The token" required:"true" env:"TEST_REQUIRED_TOKEN"`
+	Force  bool   `description:"Force the operation"`
+}
+
+func TestCli_RequiredFlags(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	flags := &RequiredFlags{}
+	ran := false
+	c.NewSubCommand("create", "create something").AddFlags(flags).Action(func() error {
+		ran = true
+		return nil
+	})
+
+	var handled error
+	c.SetErrorFunction(func(path string, err error) error {
+		handled = err
+		return err
+	})
+
+	e := c.Run("create", "--force")
+	if e == nil {
+		t.Fatalf("expected error for missing required flags")
+	}
+	if ran {
+		t.Errorf("expected action not to run")
+	}
+	expected := "missing required flags or arguments: --region, --token, <name>"
+	if handled == nil || handled.Error() != expected {
+		t.Errorf("expected error %q to be passed to error function, got %v", expected, handled)
+	}
+
+	t.Setenv("TEST_REQUIRED_TOKEN", "secret")
+	if e := c.Run("create", "bob", "--region", "eu"); e != nil {
+		t.Errorf("expected no error, got %v", e)
+	}
+	if !ran || flags.Name != "bob" || flags.Token != "secret" {
+		t.Errorf("expected action to run with flags, got %+v", flags)
+	}
+
+	// Help is shown without checking required flags
+	ran = false
+	if e := c.Run("create", "--help"); e != nil {
+		t.Errorf("expected no error, got %v", e)
+	}
+	if ran {
+		t.Errorf("expected action not to run")
+	}
+}
+
+func TestCli_RequiredImperative(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	var name string
+	var age int
+	c.StringFlag("name", "The name", &name).IntFlag("age", "The age", &age).Required("name", "age")
+	c.Action(func() error { return nil })
+
+	e := c.Run("--name", "bob")
+	if e == nil || !strings.Contains(e.Error(), "missing required flags or arguments: --age") {
+		t.Errorf("expected missing age error, got %v", e)
+	}
+
+	// Values from an earlier run do not count as set
+	if e := c.Run("--age", "3"); e == nil || !strings.Contains(e.Error(), "--name") {
+		t.Errorf("expected missing name error, got %v", e)
+	}
+}
+
+func TestCommand_usage(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	flags := &RequiredFlags{}
+	sub := c.NewSubCommand("create", "create something").AddFlags(flags)
+	expected := "test create --region <string> --token <string> [flags] <name> [count]"
+	if usage := sub.usage(); usage != expected {
+		t.Errorf("expected usage %q, got %q", expected, usage)
+	}
+	hidden := NewCli("test", "description", "0")
+	hidden.NewSubCommand("hidden", "hidden").Hidden()
+	if usage := hidden.rootCommand.usage(); usage != "test [flags]" {
+		t.Errorf("expected usage %q, got %q", "test [flags]", usage)
+	}
+}
+
+type contextKey struct{}
+
+func TestCli_RunContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+
+	c := NewCli("test", "description", "0")
+	sub := c.NewSubCommand("sub", "sub description")
+	var got interface{}
+	sub.ActionContext(func(ctx context.Context) error {
+		got = ctx.Value(contextKey{})
+		return nil
+	})
+	if e := c.RunContext(ctx, "sub"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if got != "value" {
+		t.Errorf("expected action to receive context, got %v", got)
+	}
+
+	// Plain actions still work
+	ran := false
+	sub.Action(func() error {
+		ran = true
+		return nil
+	})
+	if e := c.RunContext(ctx, "sub"); e != nil || !ran {
+		t.Errorf("expected action to run without error, got %v", e)
+	}
+}
+
+func TestCli_CommandAddFunctionContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := NewCli("test", "description", "0")
+	c.NewSubCommandFunction("create", "create a person", func(ctx context.Context, person *Person2) error {
+		if person.Name != "Bob" {
+			t.Errorf("expected name flag to be 'Bob', got %v", person.Name)
+		}
+		return ctx.Err()
+	})
+	if e := c.RunContext(ctx, "create", "-name", "Bob"); e != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", e)
+	}
+}
+
+func TestCli_ConcurrentRuns(t *testing.T) {
+	for index := 0; index < 4; index++ {
+		t.Run(fmt.Sprintf("cli %d", index), func(t *testing.T) {
+			t.Parallel()
+			var output, errOutput bytes.Buffer
+			c := NewCli("test", "description", "0").SetOutput(&output).SetErrOutput(&errOutput)
+			var name string
+			c.StringFlag("name", "The name", &name)
+			c.Action(func() error { return nil })
+			for run := 0; run < 50; run++ {
+				if err := c.Run("--unknown"); err == nil {
+					t.Fatalf("expected error for unknown flag")
+				}
+				if err := c.Run("--help"); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			}
+			if !strings.Contains(output.String(), "Usage: test [flags]") {
+				t.Errorf("expected help in output, got %q", output.String())
+			}
+			if errOutput.Len() != 0 {
+				t.Errorf("expected no error output, got %q", errOutput.String())
+			}
+		})
+	}
+}
+
+func TestCli_ResetFlags(t *testing.T) {
+	c := NewCli("test", "description", "0")
+	sub := c.NewSubCommand("sub", "sub description")
+	name := "default"
+	var tags []string
+	sub.StringFlag("name", "The name", &name)
+	sub.StringsFlag("tag", "A tag", &tags)
+	sub.Action(func() error { return nil })
+
+	if c.ExecutedCommand() != nil {
+		t.Errorf("expected no executed command before running")
+	}
+	if e := c.Run("sub", "--name", "Bob", "--tag", "a"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if c.ExecutedCommand() != sub || sub.Path() != "test sub" {
+		t.Errorf("expected sub to be the executed command, got %v", c.ExecutedCommand())
+	}
+	if values := sub.FlagValues(); values["name"] != "Bob" || values["tag"] != "[a]" {
+		t.Errorf("unexpected flag values: %v", values)
+	}
+
+	c.ResetFlags()
+	if name != "default" || len(tags) != 0 {
+		t.Errorf("expected flags to be reset, got %q and %v", name, tags)
+	}
+	if c.ExecutedCommand() != nil {
+		t.Errorf("expected executed command to be cleared")
+	}
+	if e := c.Run("sub", "--tag", "b"); e != nil || len(tags) != 1 || tags[0] != "b" {
+		t.Errorf("expected only the new tag, got %v (error %v)", tags, e)
+	}
+}
+
+func TestCli_Aliases(t *testing.T) {
+	var output bytes.Buffer
+	c := NewCli("app", "An example app", "v1.0.0").SetOutput(&output)
+	ran := ""
+	c.NewSubCommand("remove", "Remove an item").
+		Aliases("rm", "del").
+		CompleteArgs(func(prefix string, args []string) []string {
+			return []string{"apple", "banana"}
+		}).
+		Action(func() error {
+			ran = "remove"
+			return nil
+		})
+	// Aliases may be given before the command is added
+	list := NewCommand("list", "List the items").Aliases("ls")
+	list.Action(func() error {
+		ran = "list"
+		return nil
+	})
+	c.AddCommand(list)
+
+	for _, test := range [][2]string{{"rm", "remove"}, {"del", "remove"}, {"ls", "list"}} {
+		ran = ""
+		if e := c.Run(test[0]); e != nil || ran != test[1] {
+			t.Errorf("expected alias %s to run %s, got %q (error %v)", test[0], test[1], ran, e)
+		}
+	}
+
+	c.PrintHelp()
+	if !strings.Contains(output.String(), "   remove (rm, del)   Remove an item") {
+		t.Errorf("expected aliases in help, got:\n%s", output.String())
+	}
+
+	var page bytes.Buffer
+	if e := c.WriteManPage(&page); e != nil || !strings.Contains(page.String(), `\fBremove\fR, \fBrm\fR, \fBdel\fR`) {
+		t.Errorf("expected aliases in man page, got %v:\n%s", e, page.String())
+	}
+	page.Reset()
+	if e := c.WriteMarkdown(&page); e != nil || !strings.Contains(page.String(), "| [remove](app-remove.md) (`rm`, `del`) | Remove an item |") {
+		t.Errorf("expected aliases in Markdown, got %v:\n%s", e, page.String())
+	}
+
+	var script bytes.Buffer
+	if e := c.WriteCompletion(&script, "bash"); e != nil || !strings.Contains(script.String(), `':rm') cmdpath='remove'`) {
+		t.Errorf("expected aliases in completion script, got %v:\n%s", e, script.String())
+	}
+	if result := c.complete([]string{"rm", "a"}); len(result) != 1 || result[0] != "apple" {
+		t.Errorf("expected arguments of the aliased command to be completed, got %v", result)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic for duplicate alias")
+		}
+	}()
+	c.NewSubCommand("rename", "Rename an item").Aliases("rm")
+}
+
+func TestCli_PrefixMatching(t *testing.T) {
+	c := NewCli("app", "An example app", "v1.0.0")
+	ran := ""
+	for _, name := range []string{"deploy", "delete", "status"} {
+		name := name
+		c.NewSubCommand(name, name).Action(func() error {
+			ran = name
+			return nil
+		})
+	}
+	c.NewSubCommand("secret", "A hidden command").Hidden()
+
+	// Prefixes are only matched when enabled
+	if e := c.Run("dep"); e == nil {
+		t.Errorf("expected an error for a prefix without prefix matching")
+	}
+
+	c.EnablePrefixMatching()
+	if e := c.Run("dep"); e != nil || ran != "deploy" {
+		t.Errorf("expected dep to run deploy, got %q (error %v)", ran, e)
+	}
+	if e := c.Run("st"); e != nil || ran != "status" {
+		t.Errorf("expected st to run status, got %q (error %v)", ran, e)
+	}
+
+	var unknownCommand *UnknownCommandError
+	e := c.Run("de")
+	if !errors.As(e, &unknownCommand) || len(unknownCommand.Suggestions) != 2 {
+		t.Errorf("expected an ambiguous prefix to suggest both commands, got %v", e)
+	}
+	if e := c.Run("sec"); !errors.As(e, &unknownCommand) {
+		t.Errorf("expected hidden commands not to match prefixes, got %v", e)
+	}
+}
@@ -0,0 +1,107 @@
+// Package clirtest provides helpers for testing clir applications by
+// running command lines in-process and capturing the results.
+package clirtest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leaanthony/clir"
+	"github.com/leaanthony/clir/internal/hooks"
+)
+
+// UpdateEnvVar is the environment variable that, when set to a non-empty
+// value, causes AssertGolden to write golden files rather than compare
+// against them, EG: `CLIRTEST_UPDATE=1 go test ./...`
+const UpdateEnvVar = "CLIRTEST_UPDATE"
+
+// Result is the outcome of running a command line
+type Result struct {
+	// Stdout is the output printed to the application's Output
+	Stdout string
+	// Stderr is the output printed to the application's ErrOutput
+	Stderr string
+	// Err is the error returned by the run
+	Err error
+	// ExitCode is the code the application would exit with, as returned
+	// by clir.ExitCode
+	ExitCode int
+	// Command is the path of the command that was run, EG: `app db migrate`.
+	// It is blank if the run failed before a command was found.
+	Command string
+	// Flags are the final values of the command's flags, keyed by name
+	Flags map[string]string
+}
+
+// Run - Runs the application with the given arguments, capturing its
+// output. Flags are reset to their initial values before running, so the
+// same application may be run many times. Actions should print to
+// cli.Output() and cli.ErrOutput() for their output to be captured.
+func Run(cli *clir.Cli, args ...string) *Result {
+	return RunContext(context.Background(), cli, args...)
+}
+
+// RunContext - Runs the application as Run does, passing the given context
+// to actions
+func RunContext(ctx context.Context, cli *clir.Cli, args ...string) *Result {
+	var stdout, stderr bytes.Buffer
+	output, errOutput := cli.Output(), cli.ErrOutput()
+	cli.SetOutput(&stdout).SetErrOutput(&stderr)
+	defer func() {
+		cli.SetOutput(output).SetErrOutput(errOutput)
+	}()
+
+	cli.ResetFlags()
+	err := hooks.RunArgs(ctx, cli, args)
+
+	result := &Result{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Err:      err,
+		ExitCode: clir.ExitCode(err),
+		Flags:    map[string]string{},
+	}
+	if command := cli.ExecutedCommand(); command != nil {
+		result.Command = command.Path()
+		result.Flags = command.FlagValues()
+	}
+	return result
+}
+
+// AssertGolden - Compares actual with the contents of the golden file at
+// the given path, failing the test if they differ. If the environment
+// variable named by UpdateEnvVar is set, the golden file is written instead.
+func AssertGolden(t testing.TB, path string, actual string) {
+	t.Helper()
+	if os.Getenv(UpdateEnvVar) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unable to create directory for golden file %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(actual), 0644); err != nil {
+			t.Fatalf("unable to write golden file %s: %v", path, err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read golden file %s (set %s=1 to create it): %v", path, UpdateEnvVar, err)
+	}
+	if string(expected) != actual {
+		t.Errorf("output does not match golden file %s (set %s=1 to update it)\nexpected:\n%s\nactual:\n%s", path, UpdateEnvVar, expected, actual)
+	}
+}
+
+// AssertHelpGolden - Runs the given command path with `--help` and compares
+// the help printed with the golden file at the given path,
+// EG: `clirtest.AssertHelpGolden(t, cli, "testdata/deploy.golden", "deploy")`
+func AssertHelpGolden(t testing.TB, cli *clir.Cli, path string, commandPath ...string) {
+	t.Helper()
+	result := Run(cli, append(append([]string{}, commandPath...), "--help")...)
+	if result.Err != nil {
+		t.Fatalf("unable to print help: %v", result.Err)
+	}
+	AssertGolden(t, path, result.Stdout)
+}
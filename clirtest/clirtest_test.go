@@ -0,0 +1,88 @@
+package clirtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/leaanthony/clir"
+)
+
+func newTestCli() *clir.Cli {
+	cli := clir.NewCli("app", "An example app", "v1.0.0")
+	var verbose bool
+	cli.PersistentFlags().BoolFlag("verbose", "Show more output", &verbose)
+	deploy := cli.NewSubCommand("deploy", "Deploy the app")
+	region := "eu-west-1"
+	var tags []string
+	deploy.StringFlag("region", "The region to deploy to", &region)
+	deploy.StringsFlag("tag", "A tag to apply", &tags)
+	deploy.Action(func() error {
+		if region == "nowhere" {
+			return errors.New("unknown region")
+		}
+		fmt.Fprintf(cli.Output(), "deploying to %s with tags %v\n", region, tags)
+		return nil
+	})
+	return cli
+}
+
+func TestRun(t *testing.T) {
+	cli := newTestCli()
+	result := Run(cli, "deploy", "--region", "us-east-1", "--tag", "a", "--verbose")
+	if result.Err != nil || result.ExitCode != 0 {
+		t.Fatalf("expected success, got %v (exit code %d)", result.Err, result.ExitCode)
+	}
+	if result.Stdout != "deploying to us-east-1 with tags [a]\n" {
+		t.Errorf("unexpected output: %q", result.Stdout)
+	}
+	if result.Command != "app deploy" {
+		t.Errorf("expected command path 'app deploy', got %q", result.Command)
+	}
+	if result.Flags["region"] != "us-east-1" || result.Flags["verbose"] != "true" {
+		t.Errorf("unexpected flag values: %v", result.Flags)
+	}
+
+	// Values from the previous run must not leak into this one
+	result = Run(cli, "deploy", "--tag", "b")
+	if result.Stdout != "deploying to eu-west-1 with tags [b]\n" {
+		t.Errorf("expected flags to be reset, got %q", result.Stdout)
+	}
+	if result.Flags["verbose"] != "false" {
+		t.Errorf("expected verbose to be reset, got %v", result.Flags)
+	}
+
+	result = Run(cli, "deploy", "--region", "nowhere")
+	if result.Err == nil || result.ExitCode != 1 {
+		t.Errorf("expected an error and exit code 1, got %v (exit code %d)", result.Err, result.ExitCode)
+	}
+
+	result = Run(cli, "deploy", "--unknown")
+	if result.ExitCode != 2 {
+		t.Errorf("expected exit code 2 for an invalid command line, got %d", result.ExitCode)
+	}
+}
+
+func TestRun_NoArgs(t *testing.T) {
+	// os.Args holds the test binary's flags, which the run must not read
+	saved := os.Args
+	os.Args = []string{"app", "--unknown"}
+	defer func() { os.Args = saved }()
+	result := Run(newTestCli())
+	if result.Err != nil {
+		t.Fatalf("expected no error, got %v", result.Err)
+	}
+	if len(os.Args) != 2 || os.Args[1] != "--unknown" {
+		t.Errorf("expected os.Args not to be changed, got %v", os.Args)
+	}
+	if result.Command != "app" {
+		t.Errorf("expected the root command to run, got %q", result.Command)
+	}
+}
+
+func TestAssertHelpGolden(t *testing.T) {
+	cli := newTestCli()
+	AssertHelpGolden(t, cli, "testdata/app.golden")
+	AssertHelpGolden(t, cli, "testdata/deploy.golden", "deploy")
+}
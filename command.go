@@ -1,11 +1,13 @@
 package clir
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"os"
+	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -19,14 +21,54 @@ type Command struct {
 	subCommands       []*Command
 	subCommandsMap    map[string]*Command
 	longestSubcommand int
-	actionCallback    Action
+	actionCallback    ActionContext
 	app               *Cli
 	flags             *flag.FlagSet
 	flagCount         int
 	helpFlag          bool
 	hidden            bool
+	// internal commands, such as `__complete`, are run without checking
+	// required flags, flag groups or validations, or running PreRun hooks
+	internal          bool
 	positionalArgsMap map[string]reflect.Value
 	sliceSeparator    map[string]string
+	flagDetails       map[string]*flagDetail
+	shortFlags        map[string]string
+	parent            *Command
+	persistentFlags   *Command
+	// persistent is true for the flag set returned by PersistentFlags,
+	// which may only be given flags
+	persistent        bool
+	inheritedFlags    map[string]bool
+	positionalDetails map[string]*positionalDetail
+	argsCompleter     CompletionFunc
+	argCompleters     map[int]CompletionFunc
+	helpRenderer      HelpRenderer
+	aliases           []string
+	validations       []*fieldValidation
+	flagStructs       []reflect.Value
+	flagGroups        []*flagGroup
+	pendingRequires   []*pendingRequires
+}
+
+// flagDetail holds the details clir tracks for a flag that the
+// standard library flag package has no notion of
+type flagDetail struct {
+	short     string
+	env       string
+	required  bool
+	completer CompletionFunc
+	choices   []string
+	hidden    bool
+}
+
+// positionalDetail holds the details of a positional argument
+type positionalDetail struct {
+	name        string
+	description string
+	required    bool
+	// value sets the field of a value type, such as time.Duration
+	value flag.Value
 }
 
 // NewCommand creates a new Command
@@ -39,11 +81,25 @@ func NewCommand(name string, description string) *Command {
 		hidden:            false,
 		positionalArgsMap: make(map[string]reflect.Value),
 		sliceSeparator:    make(map[string]string),
+		flagDetails:       make(map[string]*flagDetail),
+		shortFlags:        make(map[string]string),
+		inheritedFlags:    make(map[string]bool),
+		positionalDetails: make(map[string]*positionalDetail),
+		argCompleters:     make(map[int]CompletionFunc),
 	}
 
 	return result
 }
 
+// newFlagSet creates the flag set for a command. The flag package's own
+// error and usage messages are discarded as errors are returned to the
+// caller and help is printed by the command.
+func newFlagSet(name string) *flag.FlagSet {
+	result := flag.NewFlagSet(name, flag.ContinueOnError)
+	result.SetOutput(io.Discard)
+	return result
+}
+
 func (c *Command) setParentCommandPath(parentCommandPath string) {
 	// Set up command path
 	if parentCommandPath != "" {
@@ -52,7 +108,7 @@ func (c *Command) setParentCommandPath(parentCommandPath string) {
 	c.commandPath += c.name
 
 	// Set up flag set
-	c.flags = flag.NewFlagSet(c.commandPath, flag.ContinueOnError)
+	c.flags = newFlagSet(c.commandPath)
 	c.BoolFlag("help", "Get help on the '"+strings.ToLower(c.commandPath)+"' command.", &c.helpFlag)
 
 	// result.Flags.Usage = result.PrintHelp
@@ -68,30 +124,157 @@ func (c *Command) inheritFlags(inheritFlags *flag.FlagSet) {
 	})
 }
 
+// PersistentFlags - Returns the set of flags that are recognised by this
+// command and all of its descendants. Flags are added to it in the same
+// way as a command, EG: `cmd.PersistentFlags().BoolFlag(...)` or
+// `cmd.PersistentFlags().AddFlags(&flags)`.
+// Persistent flags are resolved when the command is run, so they may be
+// defined before or after any subcommands.
+// Adding an action or subcommand to the returned flag set panics.
+func (c *Command) PersistentFlags() *Command {
+	c.checkNotPersistent("PersistentFlags")
+	if c.persistentFlags == nil {
+		c.persistentFlags = NewCommand(c.name, c.shortdescription)
+		c.persistentFlags.flags = newFlagSet(c.commandPath)
+		c.persistentFlags.persistent = true
+	}
+	return c.persistentFlags
+}
+
+// checkNotPersistent panics if the command is a persistent flag set, as
+// the given method would have no effect on it
+func (c *Command) checkNotPersistent(method string) {
+	if c.persistent {
+		panic(method + "() cannot be used on a persistent flag set")
+	}
+}
+
+// mergePersistentFlags adds the persistent flags of this command and its
+// ancestors to the command's flag set. Flags defined on the command itself
+// take precedence, as do those defined on the closest ancestor.
+func (c *Command) mergePersistentFlags() {
+	for ancestor := c; ancestor != nil; ancestor = ancestor.parent {
+		persistent := ancestor.persistentFlags
+		if persistent == nil {
+			continue
+		}
+		persistent.flags.VisitAll(func(f *flag.Flag) {
+			if c.flags.Lookup(f.Name) != nil {
+				return
+			}
+			c.flags.Var(f.Value, f.Name, f.Usage)
+			c.flags.Lookup(f.Name).DefValue = f.DefValue
+			c.inheritedFlags[f.Name] = true
+			if detail := persistent.flagDetails[f.Name]; detail != nil {
+				c.flagDetails[f.Name] = detail
+				if _, exists := c.shortFlags[detail.short]; detail.short != "" && !exists {
+					c.shortFlags[detail.short] = f.Name
+				}
+			}
+		})
+	}
+}
+
+// visitOwnFlags calls fn for each flag defined by the command itself,
+// either directly or as one of its persistent flags, along with the
+// flag's details
+func (c *Command) visitOwnFlags(fn func(f *flag.Flag, detail *flagDetail)) {
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if !c.inheritedFlags[f.Name] {
+			fn(f, c.flagDetails[f.Name])
+			return
+		}
+		// Persistent flags are merged starting with the command's own
+		if c.persistentFlags != nil && c.persistentFlags.flags.Lookup(f.Name) != nil {
+			fn(f, c.persistentFlags.flagDetails[f.Name])
+		}
+	})
+}
+
+// resetFlagSet discards the state of any previous parse, so that flags
+// set in an earlier run are not reported as set in this one
+func (c *Command) resetFlagSet() {
+	fresh := newFlagSet(c.flags.Name())
+	c.flags.VisitAll(func(f *flag.Flag) {
+		fresh.Var(f.Value, f.Name, f.Usage)
+		fresh.Lookup(f.Name).DefValue = f.DefValue
+	})
+	c.flags = fresh
+}
+
+// isFlagSet returns true if the named flag was set on the command line,
+// through an environment variable or by the config file. Persistent flags
+// may have been set while running an ancestor.
+func (c *Command) isFlagSet(name string) bool {
+	for command := c; command != nil; command = command.parent {
+		if command.flags == nil || command.flags.Lookup(name) == nil {
+			return false
+		}
+		set := false
+		command.flags.Visit(func(f *flag.Flag) {
+			set = set || f.Name == name
+		})
+		if set {
+			return true
+		}
+		if !command.inheritedFlags[name] {
+			return false
+		}
+	}
+	return false
+}
+
+// globalFlags returns the persistent flags that apply to this command,
+// along with the command that defines them
+func (c *Command) globalFlags() (flags []*flag.Flag, owners []*Command) {
+	seen := make(map[string]bool)
+	for ancestor := c; ancestor != nil; ancestor = ancestor.parent {
+		persistent := ancestor.persistentFlags
+		if persistent == nil {
+			continue
+		}
+		persistent.flags.VisitAll(func(f *flag.Flag) {
+			if seen[f.Name] {
+				return
+			}
+			seen[f.Name] = true
+			// Skip flags shadowed by the command's own flags
+			if local := c.flags.Lookup(f.Name); local != nil && !c.inheritedFlags[f.Name] {
+				return
+			}
+			flags = append(flags, f)
+			owners = append(owners, ancestor)
+		})
+	}
+	return flags, owners
+}
+
 func (c *Command) setApp(app *Cli) {
 	c.app = app
 }
 
 // parseFlags parses the given flags
-func (c *Command) parseFlags(args []string) error {
-	// Parse flags
-	tmp := os.Stderr
-	os.Stderr = nil
-	defer func() {
-		os.Stderr = tmp
-	}()
+func (c *Command) parseFlags(given []string) error {
+	args := c.expandShortFlags(given)
 
 	// Credit: https://stackoverflow.com/a/74146375
 	var positionalArgs []string
 	for {
-		if err := c.flags.Parse(args); err != nil {
-			return err
+		if err := c.parseCommandLine(args); err != nil {
+			return c.parseError(err, given)
 		}
 		// Consume all the flags that were parsed as flags.
-		args = args[len(args)-c.flags.NArg():]
+		consumed := len(args) - c.flags.NArg()
+		terminated := consumed > 0 && args[consumed-1] == "--"
+		args = args[consumed:]
 		if len(args) == 0 {
 			break
 		}
+		// Everything after a terminator is a positional arg
+		if terminated {
+			positionalArgs = append(positionalArgs, args...)
+			break
+		}
 		// There's at least one flag remaining and it must be a positional arg since
 		// we consumed all args that were parsed as flags. Consume just the first
 		// one, and retry parsing, since subsequent args may be flags.
@@ -101,8 +284,10 @@ func (c *Command) parseFlags(args []string) error {
 
 	// Parse just the positional args so that flagset.Args()/flagset.NArgs()
 	// return the expected value.
+	// The terminator stops positional args that look like flags from being
+	// parsed as flags.
 	// Note: This should never return an error.
-	err := c.flags.Parse(positionalArgs)
+	err := c.flags.Parse(append([]string{"--"}, positionalArgs...))
 	if err != nil {
 		return err
 	}
@@ -113,24 +298,153 @@ func (c *Command) parseFlags(args []string) error {
 	return nil
 }
 
+// expandShortFlags rewrites POSIX style short flags into their long form
+// so they can be understood by the flag package. Short flags may be
+// bundled, EG: `-xvf` and values may be attached (`-ofile.txt`) or
+// given as the next argument (`-o file.txt`).
+func (c *Command) expandShortFlags(args []string) []string {
+	if len(c.shortFlags) == 0 {
+		return args
+	}
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			result = append(result, arg)
+			continue
+		}
+
+		// Long flags are passed through untouched, but we need to skip
+		// over their value so it isn't mistaken for a flag
+		name := strings.TrimLeft(arg, "-")
+		hasValue := strings.Contains(name, "=")
+		if hasValue {
+			name = name[:strings.Index(name, "=")]
+		}
+		if f := c.flags.Lookup(name); f != nil || strings.HasPrefix(arg, "--") {
+			result = append(result, arg)
+			if f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+				result = append(result, args[i+1])
+				i++
+			}
+			continue
+		}
+
+		expanded, needsValue := c.expandShortFlagCluster(arg[1:])
+		if expanded == nil {
+			// Not a short flag cluster. Let the flag package report it.
+			result = append(result, arg)
+			continue
+		}
+		result = append(result, expanded...)
+		if needsValue && i+1 < len(args) {
+			result = append(result, args[i+1])
+			i++
+		}
+	}
+	return result
+}
+
+// expandShortFlagCluster expands a cluster of short flags, EG: `xvf`
+// into their long form. If the last flag in the cluster requires a value
+// that has not been given, needsValue is true. If any of the flags in the
+// cluster are not known short flags, nil is returned.
+func (c *Command) expandShortFlagCluster(cluster string) (expanded []string, needsValue bool) {
+	chars := []rune(cluster)
+	for index, char := range chars {
+		longName, ok := c.shortFlags[string(char)]
+		if !ok {
+			return nil, false
+		}
+		rest := string(chars[index+1:])
+		if isBoolFlag(c.flags.Lookup(longName)) {
+			if strings.HasPrefix(rest, "=") {
+				return append(expanded, "--"+longName+rest), false
+			}
+			expanded = append(expanded, "--"+longName)
+			continue
+		}
+		// The rest of the cluster is the value for this flag
+		rest = strings.TrimPrefix(rest, "=")
+		if rest == "" {
+			return append(expanded, "--"+longName), true
+		}
+		return append(expanded, "--"+longName+"="+rest), false
+	}
+	return expanded, false
+}
+
+// isBoolFlag returns true if the given flag does not require a value
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := underlyingValue(f).(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
 // Run - Runs the Command with the given arguments
 func (c *Command) run(args []string) error {
+	return c.runContext(context.Background(), args)
+}
+
+// runContext - Runs the Command with the given context and arguments
+func (c *Command) runContext(ctx context.Context, args []string) error {
+
+	if c.app != nil {
+		c.app.executed = c
+	}
+	if c.flags != nil {
+		c.resetFlagSet()
+	}
+	c.mergePersistentFlags()
+
+	// Flag values are layered: config file, environment, command line
+	if c.flags != nil {
+		if err := c.applyConfig(); err != nil {
+			return c.flagError(err)
+		}
+		if err := c.applyEnv(); err != nil {
+			return c.flagError(err)
+		}
+	}
 
 	// If we have arguments, process them
+	otherArgs := false
 	if len(args) > 0 {
+		// Consume any flags for this command that precede a subcommand,
+		// EG: `app --verbose deploy`
+		remaining, err := c.parseLeadingFlags(args)
+		if err != nil {
+			// The flags may be intended for the default command
+			if c.runsDefaultCommand() {
+				return c.app.defaultCommand.runContext(ctx, args)
+			}
+			return c.flagError(err)
+		}
+
 		// Check for subcommand
-		subcommand := c.subCommandsMap[args[0]]
-		if subcommand != nil {
-			return subcommand.run(args[1:])
+		if len(remaining) > 0 {
+			subcommand := c.findSubCommand(remaining[0])
+			if subcommand != nil {
+				if c.helpFlag {
+					c.app.executed = subcommand
+					subcommand.mergePersistentFlags()
+					subcommand.PrintHelp()
+					return nil
+				}
+				return subcommand.runContext(ctx, remaining[1:])
+			}
+			if c.requiresSubcommand() && !strings.HasPrefix(remaining[0], "-") {
+				return c.flagError(c.unknownCommand(remaining[0]))
+			}
 		}
 
 		// Parse flags
-		err := c.parseFlags(args)
+		err = c.parseFlags(remaining)
 		if err != nil {
-			if c.app.errorHandler != nil {
-				return c.app.errorHandler(c.commandPath, err)
-			}
-			return fmt.Errorf("Error: %s\nSee '%s --help' for usage", err, c.commandPath)
+			return c.flagError(err)
 		}
 
 		// Help takes precedence
@@ -138,23 +452,38 @@ func (c *Command) run(args []string) error {
 			c.PrintHelp()
 			return nil
 		}
+		otherArgs = c.flags.NArg() > 0
+
+		if otherArgs && c.requiresSubcommand() {
+			return c.flagError(c.unknownCommand(c.flags.Arg(0)))
+		}
 	}
 
 	// Do we have an action?
+	if c.actionCallback != nil && c.internal {
+		return c.actionCallback(ctx)
+	}
 	if c.actionCallback != nil {
-		return c.actionCallback()
+		if err := c.checkRequired(); err != nil {
+			return c.flagError(err)
+		}
+		if err := c.checkFlagGroups(); err != nil {
+			return c.flagError(err)
+		}
+		if err := c.validate(); err != nil {
+			return c.flagError(err)
+		}
+		if c.app != nil && c.app.verbosity != nil {
+			c.app.verbosity.apply(c.app.verbosity.count)
+		}
+		return c.actionCallback(ctx)
 	}
 
 	// If we haven't specified a subcommand
-	// check for an app level default command
-	if c.app.defaultCommand != nil {
-		// Prevent recursion!
-		if c.app.defaultCommand != c {
-			// only run default command if no args passed
-			if len(args) == 0 {
-				return c.app.defaultCommand.run(args)
-			}
-		}
+	// check for an app level default command.
+	// Only run default command if no args other than flags passed
+	if c.runsDefaultCommand() && !otherArgs {
+		return c.app.defaultCommand.runContext(ctx, nil)
 	}
 
 	// Nothing left we can do
@@ -163,52 +492,205 @@ func (c *Command) run(args []string) error {
 	return nil
 }
 
+// parseLeadingFlags parses the flags at the start of the given arguments,
+// stopping at the first non-flag argument. The unparsed arguments are returned.
+func (c *Command) parseLeadingFlags(args []string) ([]string, error) {
+	if !strings.HasPrefix(args[0], "-") || args[0] == "--" {
+		return args, nil
+	}
+
+	// Only the leading flags are expanded, as a subcommand may use the
+	// same short flags for different long flags
+	end := c.leadingFlagsEnd(args)
+	if err := c.parseCommandLine(c.expandShortFlags(args[:end])); err != nil {
+		return nil, c.parseError(err, args[:end])
+	}
+	return append(c.flags.Args(), args[end:]...), nil
+}
+
+// leadingFlagsEnd returns the index of the first argument that is not a
+// flag or a flag's value, which is the subcommand name, a positional
+// argument or a terminator
+func (c *Command) leadingFlagsEnd(args []string) int {
+	for index := 0; index < len(args); index++ {
+		arg := args[index]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			return index
+		}
+		expanded := c.expandShortFlags([]string{arg})
+		name := strings.TrimLeft(expanded[len(expanded)-1], "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := c.flags.Lookup(name); f != nil && !isBoolFlag(f) {
+			// Skip over the flag's value
+			index++
+		}
+	}
+	return len(args)
+}
+
+// checkRequired returns an error listing all the required flags that have
+// not been set and required positional arguments that have not been given
+func (c *Command) checkRequired() error {
+	if c.flags == nil {
+		return nil
+	}
+	missing := &MissingRequiredError{Command: c.commandPath}
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if detail := c.flagDetails[f.Name]; detail != nil && detail.required && !c.isFlagSet(f.Name) {
+			missing.Flags = append(missing.Flags, f.Name)
+		}
+	})
+	for _, key := range c.positionalKeys() {
+		detail := c.positionalDetails[key]
+		index, _ := strconv.Atoi(key)
+		if detail.required && c.flags.NArg() < index && !c.isFlagSet(detail.name) {
+			missing.Args = append(missing.Args, detail.name)
+		}
+	}
+	if len(missing.Flags) > 0 || len(missing.Args) > 0 {
+		return missing
+	}
+	return nil
+}
+
+// positionalKeys returns the keys of the positional arguments in order
+func (c *Command) positionalKeys() []string {
+	var keys []string
+	for key := range c.positionalDetails {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(keys[i])
+		b, _ := strconv.Atoi(keys[j])
+		return a < b
+	})
+	return keys
+}
+
+// requiresSubcommand returns true if the command has subcommands but no
+// action of its own, so any argument it is given must be a subcommand
+func (c *Command) requiresSubcommand() bool {
+	return c.actionCallback == nil && len(c.subCommands) > 0
+}
+
+// runsDefaultCommand returns true if this command should hand over to
+// the app level default command when it is not given a subcommand
+func (c *Command) runsDefaultCommand() bool {
+	// Prevent recursion!
+	return c.actionCallback == nil && c.app.defaultCommand != nil && c.app.defaultCommand != c
+}
+
+// flagError returns the error to report for an invalid command line
+func (c *Command) flagError(err error) error {
+	if c.app.errorHandler != nil {
+		return c.app.errorHandler(c.commandPath, err)
+	}
+	return &UsageError{Command: c.commandPath, Err: err}
+}
+
+// Name - Returns the name of the command
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Path - Returns the full path of the command, EG: `app db migrate`
+func (c *Command) Path() string {
+	return c.commandPath
+}
+
+// FlagValues - Returns the current value of each of the command's flags,
+// including those inherited from its ancestors, keyed by flag name
+func (c *Command) FlagValues() map[string]string {
+	result := make(map[string]string)
+	if c.flags == nil {
+		return result
+	}
+	c.mergePersistentFlags()
+	c.flags.VisitAll(func(f *flag.Flag) {
+		result[f.Name] = f.Value.String()
+	})
+	return result
+}
+
 // Action - Define an action from this command
 func (c *Command) Action(callback Action) *Command {
+	c.checkNotPersistent("Action")
+	c.actionCallback = func(context.Context) error {
+		return callback()
+	}
+	return c
+}
+
+// ActionContext - Define an action from this command that receives the
+// context the application is run with
+func (c *Command) ActionContext(callback ActionContext) *Command {
+	c.checkNotPersistent("ActionContext")
 	c.actionCallback = callback
 	return c
 }
 
 // PrintHelp - Output the help text for this command
 func (c *Command) PrintHelp() {
-	c.app.PrintBanner()
-
-	commandTitle := c.commandPath
-	if c.shortdescription != "" {
-		commandTitle += " - " + c.shortdescription
-	}
-	// Ignore root command
-	if c.commandPath != c.name {
-		fmt.Println(commandTitle)
+	if err := c.renderer().RenderHelp(c.app.Output(), c.Help()); err != nil {
+		fmt.Fprintf(c.app.ErrOutput(), "Unable to render help for '%s': %v\n", c.commandPath, err)
 	}
-	if c.longdescription != "" {
-		fmt.Println(c.longdescription + "\n")
+}
+
+// usage returns the usage line for the command, EG:
+// `app deploy --region <string> [flags] <name> [count]`
+func (c *Command) usage() string {
+	result := []string{c.commandPath}
+	for _, subcommand := range c.subCommands {
+		if !subcommand.isHidden() {
+			result = append(result, "[command]")
+			break
+		}
 	}
-	if len(c.subCommands) > 0 {
-		fmt.Println("Available commands:")
-		fmt.Println("")
-		for _, subcommand := range c.subCommands {
-			if subcommand.isHidden() {
-				continue
+	optionalFlags := false
+	if c.flags != nil {
+		c.flags.VisitAll(func(f *flag.Flag) {
+			if c.isFlagHidden(f.Name) {
+				return
+			}
+			detail := c.flagDetails[f.Name]
+			if detail == nil || !detail.required {
+				optionalFlags = true
+				return
 			}
-			spacer := strings.Repeat(" ", 3+c.longestSubcommand-len(subcommand.name))
-			isDefault := ""
-			if subcommand.isDefaultCommand() {
-				isDefault = "[default]"
+			usage := "--" + f.Name
+			if name, _ := flag.UnquoteUsage(f); name != "" {
+				usage += " <" + name + ">"
 			}
-			fmt.Printf("   %s%s%s %s\n", subcommand.name, spacer, subcommand.shortdescription, isDefault)
+			result = append(result, usage)
+		})
+	}
+	if optionalFlags {
+		result = append(result, "[flags]")
+	}
+	for _, key := range c.positionalKeys() {
+		detail := c.positionalDetails[key]
+		if detail.required {
+			result = append(result, "<"+detail.name+">")
+		} else {
+			result = append(result, "["+detail.name+"]")
 		}
-		fmt.Println("")
 	}
-	if c.flagCount > 0 {
-		fmt.Println("Flags:")
-		fmt.Println()
-		c.flags.SetOutput(os.Stdout)
-		c.flags.PrintDefaults()
-		c.flags.SetOutput(os.Stderr)
-
+	return strings.Join(result, " ")
+}
+
+// isZeroValue determines whether the default value of the flag
+// is the zero value for its type.
+func isZeroValue(f *flag.Flag) bool {
+	typ := reflect.TypeOf(underlyingValue(f))
+	var zero reflect.Value
+	if typ.Kind() == reflect.Ptr {
+		zero = reflect.New(typ.Elem())
+	} else {
+		zero = reflect.Zero(typ)
 	}
-	fmt.Println()
+	return f.DefValue == zero.Interface().(flag.Value).String()
 }
 
 // isDefaultCommand returns true if called on the default command
@@ -228,6 +710,7 @@ func (c *Command) Hidden() {
 
 // NewSubCommand - Creates a new subcommand
 func (c *Command) NewSubCommand(name, description string) *Command {
+	c.checkNotPersistent("NewSubCommand")
 	result := NewCommand(name, description)
 	c.AddCommand(result)
 	return result
@@ -235,24 +718,88 @@ func (c *Command) NewSubCommand(name, description string) *Command {
 
 // AddCommand - Adds a subcommand
 func (c *Command) AddCommand(command *Command) {
+	c.checkNotPersistent("AddCommand")
 	command.setApp(c.app)
 	command.setParentCommandPath(c.commandPath)
+	command.parent = c
 	name := command.name
 	c.subCommands = append(c.subCommands, command)
 	c.subCommandsMap[name] = command
 	if len(name) > c.longestSubcommand {
 		c.longestSubcommand = len(name)
 	}
+	for _, alias := range command.aliases {
+		c.addAlias(alias, command)
+	}
+}
+
+// Aliases - Adds alternative names that the command may be run by,
+// EG: `cmd.Aliases("rm", "del")`
+func (c *Command) Aliases(aliases ...string) *Command {
+	for _, alias := range aliases {
+		c.aliases = append(c.aliases, alias)
+		if c.parent != nil {
+			c.parent.addAlias(alias, c)
+		}
+	}
+	return c
+}
+
+// addAlias registers the given alias for the subcommand. It panics if the
+// alias is already used by another subcommand.
+func (c *Command) addAlias(alias string, command *Command) {
+	if existing := c.subCommandsMap[alias]; existing != nil && existing != command {
+		panic("Alias '" + alias + "' of command '" + command.name + "' is already used by command '" + existing.name + "'")
+	}
+	c.subCommandsMap[alias] = command
+}
+
+// findSubCommand returns the subcommand with the given name or alias. If
+// prefix matching is enabled, a prefix of the name or an alias of exactly
+// one visible subcommand also matches.
+func (c *Command) findSubCommand(name string) *Command {
+	if subcommand := c.subCommandsMap[name]; subcommand != nil {
+		return subcommand
+	}
+	if name == "" || c.app == nil || !c.app.prefixMatching {
+		return nil
+	}
+	var result *Command
+	for _, subcommand := range c.visibleSubCommands() {
+		for _, candidate := range append([]string{subcommand.name}, subcommand.aliases...) {
+			if !strings.HasPrefix(candidate, name) {
+				continue
+			}
+			if result != nil && result != subcommand {
+				// Ambiguous
+				return nil
+			}
+			result = subcommand
+		}
+	}
+	return result
 }
 
 // NewSubCommandInheritFlags - Creates a new subcommand, inherits flags from command
 func (c *Command) NewSubCommandInheritFlags(name, description string) *Command {
 	result := c.NewSubCommand(name, description)
 	result.inheritFlags(c.flags)
+	for short, name := range c.shortFlags {
+		result.ShortFlag(name, short)
+	}
 	return result
 }
 
 func (c *Command) AddFlags(optionStruct interface{}) *Command {
+	c.addFlags(optionStruct)
+	c.addPendingRequires()
+	c.checkTagGroups()
+	return c
+}
+
+// addFlags adds the fields of the struct, and any structs embedded in it,
+// as flags and positional arguments
+func (c *Command) addFlags(optionStruct interface{}) {
 	// use reflection to determine if this is a pointer to a struct
 	// if not, panic
 
@@ -268,6 +815,7 @@ func (c *Command) AddFlags(optionStruct interface{}) *Command {
 
 	// Iterate through the fields of the struct reading the struct tags
 	// and adding the flags
+	c.flagStructs = append(c.flagStructs, reflect.ValueOf(optionStruct))
 	v := reflect.ValueOf(optionStruct).Elem()
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
@@ -276,8 +824,11 @@ func (c *Command) AddFlags(optionStruct interface{}) *Command {
 			continue
 		}
 		// If this is an embedded struct, recurse
-		if fieldType.Type.Kind() == reflect.Struct {
-			c.AddFlags(field.Addr().Interface())
+		if fieldType.Type.Kind() == reflect.Struct && findValueType(fieldType.Type, "") == nil {
+			c.addFlags(field.Addr().Interface())
+			// An embedded Validate method is called through the outer
+			// struct, as it is promoted unless the outer struct has its own
+			c.flagStructs = c.flagStructs[:len(c.flagStructs)-1]
 			continue
 		}
 
@@ -287,6 +838,20 @@ func (c *Command) AddFlags(optionStruct interface{}) *Command {
 		defaultValue := tag.Get("default")
 		pos := tag.Get("pos")
 		sep := tag.Get("sep")
+		short := tag.Get("short")
+		env := tag.Get("env")
+		enum := tag.Get("enum")
+		xor := tag.Get("xor")
+		exclusive := tag.Get("exclusive")
+		requires := tag.Get("requires")
+		required := false
+		if requiredTag := tag.Get("required"); requiredTag != "" {
+			var err error
+			required, err = strconv.ParseBool(requiredTag)
+			if err != nil {
+				panic("Invalid value for required tag on field " + fieldType.Name)
+			}
+		}
 		c.positionalArgsMap[pos] = field
 		if sep != "" {
 			c.sliceSeparator[pos] = sep
@@ -294,360 +859,128 @@ func (c *Command) AddFlags(optionStruct interface{}) *Command {
 		if name == "" {
 			name = strings.ToLower(t.Elem().Field(i).Name)
 		}
-		switch field.Kind() {
-		case reflect.Bool:
-			var defaultValueBool bool
-			if defaultValue != "" {
-				var err error
-				defaultValueBool, err = strconv.ParseBool(defaultValue)
-				if err != nil {
-					panic("Invalid default value for bool flag")
-				}
-			}
-			field.SetBool(defaultValueBool)
-			c.BoolFlag(name, description, field.Addr().Interface().(*bool))
-		case reflect.String:
-			if defaultValue != "" {
-				// set value of field to default value
-				field.SetString(defaultValue)
-			}
-			c.StringFlag(name, description, field.Addr().Interface().(*string))
-		case reflect.Int:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for int flag")
-				}
-				field.SetInt(int64(value))
-			}
-			c.IntFlag(name, description, field.Addr().Interface().(*int))
-		case reflect.Int8:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for int8 flag")
-				}
-				field.SetInt(int64(value))
-			}
-			c.Int8Flag(name, description, field.Addr().Interface().(*int8))
-		case reflect.Int16:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for int16 flag")
-				}
-				field.SetInt(int64(value))
-			}
-			c.Int16Flag(name, description, field.Addr().Interface().(*int16))
-		case reflect.Int32:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for int32 flag")
-				}
-				field.SetInt(int64(value))
-			}
-			c.Int32Flag(name, description, field.Addr().Interface().(*int32))
-		case reflect.Int64:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for int64 flag")
-				}
-				field.SetInt(int64(value))
-			}
-			c.Int64Flag(name, description, field.Addr().Interface().(*int64))
-		case reflect.Uint:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for uint flag")
-				}
-				field.SetUint(uint64(value))
-			}
-			c.UintFlag(name, description, field.Addr().Interface().(*uint))
-		case reflect.Uint8:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for uint8 flag")
-				}
-				field.SetUint(uint64(value))
-			}
-			c.Uint8Flag(name, description, field.Addr().Interface().(*uint8))
-		case reflect.Uint16:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for uint16 flag")
-				}
-				field.SetUint(uint64(value))
-			}
-			c.Uint16Flag(name, description, field.Addr().Interface().(*uint16))
-		case reflect.Uint32:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for uint32 flag")
-				}
-				field.SetUint(uint64(value))
-			}
-			c.Uint32Flag(name, description, field.Addr().Interface().(*uint32))
-		case reflect.Uint64:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.Atoi(defaultValue)
-				if err != nil {
-					panic("Invalid default value for uint64 flag")
-				}
-				field.SetUint(uint64(value))
-			}
-			c.UInt64Flag(name, description, field.Addr().Interface().(*uint64))
-		case reflect.Float32:
-			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.ParseFloat(defaultValue, 64)
-				if err != nil {
-					panic("Invalid default value for float32 flag")
-				}
-				field.SetFloat(value)
+		if pos != "" {
+			c.positionalDetails[pos] = &positionalDetail{
+				name:        name,
+				description: description,
+				required:    required,
 			}
-			c.Float32Flag(name, description, field.Addr().Interface().(*float32))
-		case reflect.Float64:
+		}
+		switch valueType := findValueType(field.Type(), tag.Get("layout")); {
+		case valueType != nil:
+			c.addValueField(name, description, defaultValue, sep, pos, field, valueType)
+		case tag.Get("type") == "count":
+			c.addCountField(name, description, defaultValue, field)
+		case field.Kind() == reflect.Map:
+			c.addMapField(name, description, defaultValue, sep, pos, field)
+		case isBasicType(field.Type()):
 			if defaultValue != "" {
-				// set value of field to default value
-				value, err := strconv.ParseFloat(defaultValue, 64)
-				if err != nil {
-					panic("Invalid default value for float64 flag")
+				if err := setBasic(field, defaultValue, sep); err != nil {
+					panic("Invalid default value for " + basicKind(field.Type()).String() + " flag")
 				}
-				field.SetFloat(value)
 			}
-			c.Float64Flag(name, description, field.Addr().Interface().(*float64))
-		case reflect.Slice:
-			c.addSliceField(field, defaultValue, sep)
-			c.addSliceFlags(name, description, field)
+			c.addFlag(name, description, field)
 		default:
 			if pos != "" {
 				println("WARNING: Unsupported type for flag: ", fieldType.Type.Kind(), name)
 			}
 		}
-	}
-
-	return c
-}
-
-func (c *Command) addSliceFlags(name, description string, field reflect.Value) *Command {
-	if field.Kind() != reflect.Slice {
-		panic("addSliceFlags() requires a pointer to a slice")
-	}
-	t := reflect.TypeOf(field.Addr().Interface())
-	if t.Kind() != reflect.Ptr {
-		panic("addSliceFlags() requires a pointer to a slice")
-	}
-	if t.Elem().Kind() != reflect.Slice {
-		panic("addSliceFlags() requires a pointer to a slice")
-	}
-	switch t.Elem().Elem().Kind() {
-	case reflect.Bool:
-		c.BoolsFlag(name, description, field.Addr().Interface().(*[]bool))
-	case reflect.String:
-		c.StringsFlag(name, description, field.Addr().Interface().(*[]string))
-	case reflect.Int:
-		c.IntsFlag(name, description, field.Addr().Interface().(*[]int))
-	case reflect.Int8:
-		c.Int8sFlag(name, description, field.Addr().Interface().(*[]int8))
-	case reflect.Int16:
-		c.Int16sFlag(name, description, field.Addr().Interface().(*[]int16))
-	case reflect.Int32:
-		c.Int32sFlag(name, description, field.Addr().Interface().(*[]int32))
-	case reflect.Int64:
-		c.Int64sFlag(name, description, field.Addr().Interface().(*[]int64))
-	case reflect.Uint:
-		c.UintsFlag(name, description, field.Addr().Interface().(*[]uint))
-	case reflect.Uint8:
-		c.Uint8sFlag(name, description, field.Addr().Interface().(*[]uint8))
-	case reflect.Uint16:
-		c.Uint16sFlag(name, description, field.Addr().Interface().(*[]uint16))
-	case reflect.Uint32:
-		c.Uint32sFlag(name, description, field.Addr().Interface().(*[]uint32))
-	case reflect.Uint64:
-		c.Uint64sFlag(name, description, field.Addr().Interface().(*[]uint64))
-	case reflect.Float32:
-		c.Float32sFlag(name, description, field.Addr().Interface().(*[]float32))
-	case reflect.Float64:
-		c.Float64sFlag(name, description, field.Addr().Interface().(*[]float64))
-	default:
-		panic(fmt.Sprintf("addSliceFlags() not supported slice type %s", t.Elem().Elem().Kind().String()))
-	}
-	return c
-}
-
-func (c *Command) addSliceField(field reflect.Value, defaultValue, separator string) *Command {
-	if defaultValue == "" {
-		return c
-	}
-	if field.Kind() != reflect.Slice {
-		panic("addSliceField() requires a pointer to a slice")
-	}
-	t := reflect.TypeOf(field.Addr().Interface())
-	if t.Kind() != reflect.Ptr {
-		panic("addSliceField() requires a pointer to a slice")
-	}
-	if t.Elem().Kind() != reflect.Slice {
-		panic("addSliceField() requires a pointer to a slice")
-	}
-	defaultSlice := []string{defaultValue}
-	if separator != "" {
-		defaultSlice = strings.Split(defaultValue, separator)
-	}
-	switch t.Elem().Elem().Kind() {
-	case reflect.Bool:
-		defaultValues := make([]bool, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.ParseBool(value)
-			if err != nil {
-				panic("Invalid default value for bool flag")
-			}
-			defaultValues = append(defaultValues, val)
-		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.String:
-		field.Set(reflect.ValueOf(defaultSlice))
-	case reflect.Int:
-		defaultValues := make([]int, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for int flag")
-			}
-			defaultValues = append(defaultValues, val)
-		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Int8:
-		defaultValues := make([]int8, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for int8 flag")
-			}
-			defaultValues = append(defaultValues, int8(val))
-		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Int16:
-		defaultValues := make([]int16, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for int16 flag")
-			}
-			defaultValues = append(defaultValues, int16(val))
-		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Int32:
-		defaultValues := make([]int32, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.ParseInt(value, 10, 32)
-			if err != nil {
-				panic("Invalid default value for int32 flag")
-			}
-			defaultValues = append(defaultValues, int32(val))
+		if c.flags.Lookup(name) == nil {
+			continue
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Int64:
-		defaultValues := make([]int64, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.ParseInt(value, 10, 64)
-			if err != nil {
-				panic("Invalid default value for int64 flag")
-			}
-			defaultValues = append(defaultValues, val)
+		if short != "" {
+			c.ShortFlag(name, short)
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Uint:
-		defaultValues := make([]uint, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for uint flag")
-			}
-			defaultValues = append(defaultValues, uint(val))
+		if env != "" {
+			c.EnvVar(name, env)
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Uint8:
-		defaultValues := make([]uint8, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for uint8 flag")
-			}
-			defaultValues = append(defaultValues, uint8(val))
+		if enum != "" {
+			c.Choices(name, strings.Split(enum, ",")...)
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Uint16:
-		defaultValues := make([]uint16, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for uint16 flag")
-			}
-			defaultValues = append(defaultValues, uint16(val))
+		if required && pos == "" {
+			c.Required(name)
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Uint32:
-		defaultValues := make([]uint32, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for uint32 flag")
-			}
-			defaultValues = append(defaultValues, uint32(val))
+		if xor != "" {
+			c.addTagGroup(RuleExactlyOne, xor, name)
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Uint64:
-		defaultValues := make([]uint64, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for uint64 flag")
-			}
-			defaultValues = append(defaultValues, uint64(val))
+		if exclusive != "" {
+			c.addTagGroup(RuleAtMostOne, exclusive, name)
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Float32:
-		defaultValues := make([]float32, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for float32 flag")
-			}
-			defaultValues = append(defaultValues, float32(val))
+		if requires != "" {
+			c.pendingRequires = append(c.pendingRequires, &pendingRequires{field: fieldType.Name, flag: name, required: strings.Split(requires, ",")})
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	case reflect.Float64:
-		defaultValues := make([]float64, 0, len(defaultSlice))
-		for _, value := range defaultSlice {
-			val, err := strconv.Atoi(value)
-			if err != nil {
-				panic("Invalid default value for float64 flag")
-			}
-			defaultValues = append(defaultValues, float64(val))
+		c.addValidation(name, pos, field, fieldType)
+	}
+}
+
+// ShortFlag - Adds a single character alias for the named flag, so that
+// it may be given as `-v` or bundled with other short flags, EG: `-xvf`
+func (c *Command) ShortFlag(name, short string) *Command {
+	if c.flags.Lookup(name) == nil {
+		panic("ShortFlag() requires flag '" + name + "' to be defined first")
+	}
+	if len([]rune(short)) != 1 {
+		panic("ShortFlag() requires a single character short name for flag '" + name + "'")
+	}
+	if existing, ok := c.shortFlags[short]; ok && existing != name {
+		panic("ShortFlag() short name '" + short + "' already used by flag '" + existing + "'")
+	}
+	c.shortFlags[short] = name
+	c.flagDetail(name).short = short
+	return c
+}
+
+// EnvVar - Binds the named flag to the given environment variable. If the
+// flag is not given on the command line, the value of the environment
+// variable is used instead of the default value
+func (c *Command) EnvVar(name, envVar string) *Command {
+	if c.flags.Lookup(name) == nil {
+		panic("EnvVar() requires flag '" + name + "' to be defined first")
+	}
+	c.flagDetail(name).env = envVar
+	return c
+}
+
+// Required - Marks the named flags as required. The command will fail
+// with an error listing every required flag that has not been set
+func (c *Command) Required(names ...string) *Command {
+	for _, name := range names {
+		if c.flags.Lookup(name) == nil {
+			panic("Required() requires flag '" + name + "' to be defined first")
+		}
+		c.flagDetail(name).required = true
+	}
+	return c
+}
+
+// HideFlags - Hides the named flags from the help text, generated
+// documentation, shell completion and suggestions. They may still be given.
+func (c *Command) HideFlags(names ...string) *Command {
+	for _, name := range names {
+		if c.flags.Lookup(name) == nil {
+			panic("HideFlags() requires flag '" + name + "' to be defined first")
 		}
-		field.Set(reflect.ValueOf(defaultValues))
-	default:
-		panic(fmt.Sprintf("addSliceField() not supported slice type %s", t.Elem().Elem().Kind().String()))
+		c.flagDetail(name).hidden = true
 	}
 	return c
 }
 
+// isFlagHidden returns true if the named flag has been hidden
+func (c *Command) isFlagHidden(name string) bool {
+	detail := c.flagDetails[name]
+	return detail != nil && detail.hidden
+}
+
+// flagDetail returns the details for the named flag, creating them if needed
+func (c *Command) flagDetail(name string) *flagDetail {
+	detail := c.flagDetails[name]
+	if detail == nil {
+		detail = &flagDetail{}
+		c.flagDetails[name] = detail
+	}
+	return detail
+}
+
 // BoolFlag - Adds a boolean flag to the command
 func (c *Command) BoolFlag(name, description string, variable *bool) *Command {
 	c.flags.BoolVar(variable, name, *variable, description)
@@ -657,7 +990,7 @@ func (c *Command) BoolFlag(name, description string, variable *bool) *Command {
 
 // BoolsFlag - Adds a booleans flag to the command
 func (c *Command) BoolsFlag(name, description string, variable *[]bool) *Command {
-	c.flags.Var(newBoolsValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
@@ -671,7 +1004,7 @@ func (c *Command) StringFlag(name, description string, variable *string) *Comman
 
 // StringsFlag - Adds a strings flag to the command
 func (c *Command) StringsFlag(name, description string, variable *[]string) *Command {
-	c.flags.Var(newStringsValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
@@ -685,49 +1018,49 @@ func (c *Command) IntFlag(name, description string, variable *int) *Command {
 
 // IntsFlag - Adds an ints flag to the command
 func (c *Command) IntsFlag(name, description string, variable *[]int) *Command {
-	c.flags.Var(newIntsValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Int8Flag - Adds an int8 flag to the command
 func (c *Command) Int8Flag(name, description string, variable *int8) *Command {
-	c.flags.Var(newInt8Value(*variable, variable), name, description)
+	c.flags.Var(newBasicValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Int8sFlag - Adds an int8 s flag to the command
 func (c *Command) Int8sFlag(name, description string, variable *[]int8) *Command {
-	c.flags.Var(newInt8sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Int16Flag - Adds an int16 flag to the command
 func (c *Command) Int16Flag(name, description string, variable *int16) *Command {
-	c.flags.Var(newInt16Value(*variable, variable), name, description)
+	c.flags.Var(newBasicValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Int16sFlag - Adds an int16s flag to the command
 func (c *Command) Int16sFlag(name, description string, variable *[]int16) *Command {
-	c.flags.Var(newInt16sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Int32Flag - Adds an int32 flag to the command
 func (c *Command) Int32Flag(name, description string, variable *int32) *Command {
-	c.flags.Var(newInt32Value(*variable, variable), name, description)
+	c.flags.Var(newBasicValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Int32sFlag - Adds an int32s flag to the command
 func (c *Command) Int32sFlag(name, description string, variable *[]int32) *Command {
-	c.flags.Var(newInt32sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
@@ -741,7 +1074,7 @@ func (c *Command) Int64Flag(name, description string, variable *int64) *Command
 
 // Int64sFlag - Adds an int64s flag to the command
 func (c *Command) Int64sFlag(name, description string, variable *[]int64) *Command {
-	c.flags.Var(newInt64sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
@@ -755,49 +1088,49 @@ func (c *Command) UintFlag(name, description string, variable *uint) *Command {
 
 // UintsFlag - Adds an uints flag to the command
 func (c *Command) UintsFlag(name, description string, variable *[]uint) *Command {
-	c.flags.Var(newUintsValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Uint8Flag - Adds an uint8 flag to the command
 func (c *Command) Uint8Flag(name, description string, variable *uint8) *Command {
-	c.flags.Var(newUint8Value(*variable, variable), name, description)
+	c.flags.Var(newBasicValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Uint8sFlag - Adds an uint8 s flag to the command
 func (c *Command) Uint8sFlag(name, description string, variable *[]uint8) *Command {
-	c.flags.Var(newUint8sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Uint16Flag - Adds an uint16 flag to the command
 func (c *Command) Uint16Flag(name, description string, variable *uint16) *Command {
-	c.flags.Var(newUint16Value(*variable, variable), name, description)
+	c.flags.Var(newBasicValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Uint16sFlag - Adds an uint16s flag to the command
 func (c *Command) Uint16sFlag(name, description string, variable *[]uint16) *Command {
-	c.flags.Var(newUint16sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Uint32Flag - Adds an uint32 flag to the command
 func (c *Command) Uint32Flag(name, description string, variable *uint32) *Command {
-	c.flags.Var(newUint32Value(*variable, variable), name, description)
+	c.flags.Var(newBasicValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Uint32sFlag - Adds an uint32s flag to the command
 func (c *Command) Uint32sFlag(name, description string, variable *[]uint32) *Command {
-	c.flags.Var(newUint32sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
@@ -811,7 +1144,7 @@ func (c *Command) UInt64Flag(name, description string, variable *uint64) *Comman
 
 // Uint64sFlag - Adds an uint64s flag to the command
 func (c *Command) Uint64sFlag(name, description string, variable *[]uint64) *Command {
-	c.flags.Var(newUint64sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
@@ -825,421 +1158,25 @@ func (c *Command) Float64Flag(name, description string, variable *float64) *Comm
 
 // Float32Flag - Adds a float32 flag to the command
 func (c *Command) Float32Flag(name, description string, variable *float32) *Command {
-	c.flags.Var(newFloat32Value(*variable, variable), name, description)
+	c.flags.Var(newBasicValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Float32sFlag - Adds a float32s flag to the command
 func (c *Command) Float32sFlag(name, description string, variable *[]float32) *Command {
-	c.flags.Var(newFloat32sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
 // Float64sFlag - Adds a float64s flag to the command
 func (c *Command) Float64sFlag(name, description string, variable *[]float64) *Command {
-	c.flags.Var(newFloat64sValue(*variable, variable), name, description)
+	c.flags.Var(newSliceValue(*variable, variable), name, description)
 	c.flagCount++
 	return c
 }
 
-type boolsFlagVar []bool
-
-func (f *boolsFlagVar) String() string { return fmt.Sprint([]bool(*f)) }
-
-func (f *boolsFlagVar) Set(value string) error {
-	if value == "" {
-		*f = append(*f, false)
-		return nil
-	}
-	b, err := strconv.ParseBool(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, b)
-	return nil
-}
-
-func (f *boolsFlagVar) IsBoolFlag() bool {
-	return true
-}
-
-func newBoolsValue(val []bool, p *[]bool) *boolsFlagVar {
-	*p = val
-	return (*boolsFlagVar)(p)
-}
-
-type stringsFlagVar []string
-
-func (f *stringsFlagVar) String() string { return fmt.Sprint([]string(*f)) }
-
-func (f *stringsFlagVar) Set(value string) error {
-	*f = append(*f, value)
-	return nil
-}
-
-func newStringsValue(val []string, p *[]string) *stringsFlagVar {
-	*p = val
-	return (*stringsFlagVar)(p)
-}
-
-type intsFlagVar []int
-
-func (f *intsFlagVar) String() string { return fmt.Sprint([]int(*f)) }
-
-func (f *intsFlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, i)
-	return nil
-}
-
-func newIntsValue(val []int, p *[]int) *intsFlagVar {
-	*p = val
-	return (*intsFlagVar)(p)
-}
-
-type int8Value int8
-
-func newInt8Value(val int8, p *int8) *int8Value {
-	*p = val
-	return (*int8Value)(p)
-}
-
-func (f *int8Value) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = int8Value(i)
-	return nil
-}
-
-func (f *int8Value) String() string { return fmt.Sprint(int8(*f)) }
-
-type int8sFlagVar []int8
-
-func (f *int8sFlagVar) String() string { return fmt.Sprint([]int8(*f)) }
-
-func (f *int8sFlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, int8(i))
-	return nil
-}
-
-func newInt8sValue(val []int8, p *[]int8) *int8sFlagVar {
-	*p = val
-	return (*int8sFlagVar)(p)
-}
-
-type int16Value int16
-
-func newInt16Value(val int16, p *int16) *int16Value {
-	*p = val
-	return (*int16Value)(p)
-}
-
-func (f *int16Value) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = int16Value(i)
-	return nil
-}
-
-func (f *int16Value) String() string { return fmt.Sprint(int16(*f)) }
-
-type int16sFlagVar []int16
-
-func (f *int16sFlagVar) String() string { return fmt.Sprint([]int16(*f)) }
-
-func (f *int16sFlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, int16(i))
-	return nil
-}
-
-func newInt16sValue(val []int16, p *[]int16) *int16sFlagVar {
-	*p = val
-	return (*int16sFlagVar)(p)
-}
-
-type int32Value int32
-
-func newInt32Value(val int32, p *int32) *int32Value {
-	*p = val
-	return (*int32Value)(p)
-}
-
-func (f *int32Value) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = int32Value(i)
-	return nil
-}
-
-func (f *int32Value) String() string { return fmt.Sprint(int32(*f)) }
-
-type int32sFlagVar []int32
-
-func (f *int32sFlagVar) String() string { return fmt.Sprint([]int32(*f)) }
-
-func (f *int32sFlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, int32(i))
-	return nil
-}
-
-func newInt32sValue(val []int32, p *[]int32) *int32sFlagVar {
-	*p = val
-	return (*int32sFlagVar)(p)
-}
-
-type int64sFlagVar []int64
-
-func (f *int64sFlagVar) String() string { return fmt.Sprint([]int64(*f)) }
-
-func (f *int64sFlagVar) Set(value string) error {
-	i, err := strconv.ParseInt(value, 10, 64)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, i)
-	return nil
-}
-
-func newInt64sValue(val []int64, p *[]int64) *int64sFlagVar {
-	*p = val
-	return (*int64sFlagVar)(p)
-}
-
-type uintsFlagVar []uint
-
-func (f *uintsFlagVar) String() string {
-	return fmt.Sprint([]uint(*f))
-}
-
-func (f *uintsFlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, uint(i))
-	return nil
-}
-
-func newUintsValue(val []uint, p *[]uint) *uintsFlagVar {
-	*p = val
-	return (*uintsFlagVar)(p)
-}
-
-type uint8FlagVar uint8
-
-func newUint8Value(val uint8, p *uint8) *uint8FlagVar {
-	*p = val
-	return (*uint8FlagVar)(p)
-}
-
-func (f *uint8FlagVar) String() string {
-	return fmt.Sprint(uint8(*f))
-}
-
-func (f *uint8FlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = uint8FlagVar(i)
-	return nil
-}
-
-type uint8sFlagVar []uint8
-
-func (f *uint8sFlagVar) String() string {
-	return fmt.Sprint([]uint8(*f))
-}
-
-func (f *uint8sFlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, uint8(i))
-	return nil
-}
-
-func newUint8sValue(val []uint8, p *[]uint8) *uint8sFlagVar {
-	*p = val
-	return (*uint8sFlagVar)(p)
-}
-
-type uint16FlagVar uint16
-
-func newUint16Value(val uint16, p *uint16) *uint16FlagVar {
-	*p = val
-	return (*uint16FlagVar)(p)
-}
-
-func (f *uint16FlagVar) String() string {
-	return fmt.Sprint(uint16(*f))
-}
-
-func (f *uint16FlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = uint16FlagVar(i)
-	return nil
-}
-
-type uint16sFlagVar []uint16
-
-func (f *uint16sFlagVar) String() string {
-	return fmt.Sprint([]uint16(*f))
-}
-
-func (f *uint16sFlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, uint16(i))
-	return nil
-}
-
-func newUint16sValue(val []uint16, p *[]uint16) *uint16sFlagVar {
-	*p = val
-	return (*uint16sFlagVar)(p)
-}
-
-type uint32FlagVar uint32
-
-func newUint32Value(val uint32, p *uint32) *uint32FlagVar {
-	*p = val
-	return (*uint32FlagVar)(p)
-}
-
-func (f *uint32FlagVar) String() string {
-	return fmt.Sprint(uint32(*f))
-}
-
-func (f *uint32FlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = uint32FlagVar(i)
-	return nil
-}
-
-type uint32sFlagVar []uint32
-
-func (f *uint32sFlagVar) String() string {
-	return fmt.Sprint([]uint32(*f))
-}
-
-func (f *uint32sFlagVar) Set(value string) error {
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, uint32(i))
-	return nil
-}
-
-func newUint32sValue(val []uint32, p *[]uint32) *uint32sFlagVar {
-	*p = val
-	return (*uint32sFlagVar)(p)
-}
-
-type uint64sFlagVar []uint64
-
-func (f *uint64sFlagVar) String() string { return fmt.Sprint([]uint64(*f)) }
-
-func (f *uint64sFlagVar) Set(value string) error {
-	i, err := strconv.ParseUint(value, 10, 64)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, i)
-	return nil
-}
-
-func newUint64sValue(val []uint64, p *[]uint64) *uint64sFlagVar {
-	*p = val
-	return (*uint64sFlagVar)(p)
-}
-
-type float32sFlagVar []float32
-
-func (f *float32sFlagVar) String() string { return fmt.Sprint([]float32(*f)) }
-
-func (f *float32sFlagVar) Set(value string) error {
-	i, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, float32(i))
-	return nil
-}
-
-func newFloat32sValue(val []float32, p *[]float32) *float32sFlagVar {
-	*p = val
-	return (*float32sFlagVar)(p)
-}
-
-type float32FlagVar float32
-
-func (f *float32FlagVar) String() string { return fmt.Sprint(float32(*f)) }
-
-func (f *float32FlagVar) Set(value string) error {
-	i, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return err
-	}
-	*f = float32FlagVar(i)
-	return nil
-}
-
-func newFloat32Value(val float32, p *float32) *float32FlagVar {
-	*p = val
-	return (*float32FlagVar)(p)
-}
-
-type float64sFlagVar []float64
-
-func (f *float64sFlagVar) String() string { return fmt.Sprint([]float64(*f)) }
-
-func (f *float64sFlagVar) Set(value string) error {
-	i, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return err
-	}
-	*f = append(*f, i)
-	return nil
-}
-
-func newFloat64sValue(val []float64, p *[]float64) *float64sFlagVar {
-	*p = val
-	return (*float64sFlagVar)(p)
-}
-
 // LongDescription - Sets the long description for the command
 func (c *Command) LongDescription(longdescription string) *Command {
 	c.longdescription = longdescription
@@ -1251,6 +1188,10 @@ func (c *Command) OtherArgs() []string {
 	return c.flags.Args()
 }
 
+// NewSubCommandFunction - Creates a new subcommand that calls the given
+// function with the flags defined by its struct argument. The function
+// must have the signature `func(*struct) error` or
+// `func(context.Context, *struct) error`
 func (c *Command) NewSubCommandFunction(name string, description string, fn interface{}) *Command {
 	result := c.NewSubCommand(name, description)
 	// use reflection to determine if this is a function
@@ -1260,16 +1201,19 @@ func (c *Command) NewSubCommandFunction(name string, description string, fn inte
 		panic("NewSubFunction '" + name + "' requires a function with the signature 'func(*struct) error'")
 	}
 
-	// Check the function has 1 input ant it's a struct pointer
+	// Check the function has 1 input and it's a struct pointer,
+	// optionally preceded by a context
 	fnValue := reflect.ValueOf(fn)
-	if t.NumIn() != 1 {
+	hasContext := t.NumIn() == 2 && t.In(0) == reflect.TypeOf((*context.Context)(nil)).Elem()
+	if t.NumIn() != 1 && !hasContext {
 		panic("NewSubFunction '" + name + "' requires a function with the signature 'func(*struct) error'")
 	}
+	flagsType := t.In(t.NumIn() - 1)
 	// Check the input is a struct pointer
-	if t.In(0).Kind() != reflect.Ptr {
+	if flagsType.Kind() != reflect.Ptr {
 		panic("NewSubFunction '" + name + "' requires a function with the signature 'func(*struct) error'")
 	}
-	if t.In(0).Elem().Kind() != reflect.Struct {
+	if flagsType.Elem().Kind() != reflect.Struct {
 		panic("NewSubFunction '" + name + "' requires a function with the signature 'func(*struct) error'")
 	}
 	// Check only 1 output and it's an error
@@ -1279,9 +1223,13 @@ func (c *Command) NewSubCommandFunction(name string, description string, fn inte
 	if t.Out(0) != reflect.TypeOf((*error)(nil)).Elem() {
 		panic("NewSubFunction '" + name + "' requires a function with the signature 'func(*struct) error'")
 	}
-	flags := reflect.New(t.In(0).Elem())
-	result.Action(func() error {
-		result := fnValue.Call([]reflect.Value{flags})[0].Interface()
+	flags := reflect.New(flagsType.Elem())
+	result.ActionContext(func(ctx context.Context) error {
+		args := []reflect.Value{flags}
+		if hasContext {
+			args = []reflect.Value{reflect.ValueOf(ctx), flags}
+		}
+		result := fnValue.Call(args)[0].Interface()
 		if result != nil {
 			return result.(error)
 		}
@@ -1299,6 +1247,15 @@ func (c *Command) parsePositionalArgs(args []string) error {
 		if !ok {
 			continue
 		}
+		if choices := c.positionalChoices(key); len(choices) > 0 && !isChoice(posArg, choices) {
+			return c.invalidArg(key, posArg, choiceError(choices))
+		}
+		if detail := c.positionalDetails[key]; detail != nil && detail.value != nil {
+			if err := setPositional(detail.value, posArg, c.sliceSeparator[key]); err != nil {
+				return c.invalidArg(key, posArg, err)
+			}
+			continue
+		}
 		fieldType := field.Type()
 		switch fieldType.Kind() {
 		case reflect.Bool:
@@ -1309,26 +1266,48 @@ func (c *Command) parsePositionalArgs(args []string) error {
 		case reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8, reflect.Int:
 			value, err := strconv.ParseInt(posArg, 10, 64)
 			if err != nil {
-				return err
+				return c.invalidArg(key, posArg, err)
 			}
 			field.SetInt(value)
 		case reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8, reflect.Uint:
 			value, err := strconv.ParseUint(posArg, 10, 64)
 			if err != nil {
-				return err
+				return c.invalidArg(key, posArg, err)
 			}
 			field.SetUint(value)
 		case reflect.Float64, reflect.Float32:
 			value, err := strconv.ParseFloat(posArg, 64)
 			if err != nil {
-				return err
+				return c.invalidArg(key, posArg, err)
 			}
 			field.SetFloat(value)
 		case reflect.Slice:
-			c.addSliceField(field, posArg, c.sliceSeparator[key])
+			if err := setBasic(field, posArg, c.sliceSeparator[key]); err != nil {
+				return c.invalidArg(key, posArg, err)
+			}
 		default:
 			return errors.New("Unsupported type for positional argument: " + fieldType.Name())
 		}
 	}
 	return nil
 }
+
+// positionalChoices returns the choices of the positional argument with
+// the given key, if it is restricted to a set
+func (c *Command) positionalChoices(key string) []string {
+	detail := c.positionalDetails[key]
+	if detail == nil || c.flagDetails[detail.name] == nil {
+		return nil
+	}
+	return c.flagDetails[detail.name].choices
+}
+
+// invalidArg returns the error for a value that could not be parsed for
+// the positional argument with the given key
+func (c *Command) invalidArg(key, value string, err error) error {
+	name := key
+	if detail := c.positionalDetails[key]; detail != nil {
+		name = detail.name
+	}
+	return &InvalidValueError{Command: c.commandPath, Arg: name, Value: value, Err: err}
+}
@@ -0,0 +1,780 @@
+package clir
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// completionShells are the shells that completion scripts can be generated for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// CompletionFunc returns the completion candidates for a flag value or
+// positional argument. prefix is the partial word being completed and args
+// are the positional arguments given so far. Candidates that do not start
+// with prefix are discarded.
+type CompletionFunc func(prefix string, args []string) []string
+
+// completionCommand is the information about a command needed to
+// generate completion scripts
+type completionCommand struct {
+	// path is the command path without the application name, EG: `db migrate`
+	path        string
+	name        string
+	aliases     []string
+	description string
+	subcommands []*completionCommand
+	flags       []*completionFlag
+	// positional is true if the command accepts positional arguments,
+	// which are completed as file paths
+	positional bool
+	// dynamicArgs is true if positional arguments are completed by the
+	// application at runtime
+	dynamicArgs bool
+}
+
+// completionFlag is the information about a flag needed to generate
+// completion scripts
+type completionFlag struct {
+	name        string
+	short       string
+	description string
+	takesValue  bool
+	// dynamic is true if the flag's value is completed by the application
+	// at runtime
+	dynamic bool
+	// choices are the values the flag accepts, if it is restricted to a set
+	choices []string
+}
+
+// names returns the forms the flag may be given in, EG: `--verbose`, `-v`
+func (f *completionFlag) names() []string {
+	result := []string{"--" + f.name}
+	if f.short != "" {
+		result = append(result, "-"+f.short)
+	}
+	return result
+}
+
+// EnableCompletion - Adds a hidden `completion` subcommand that outputs the
+// completion script for the given shell, EG: `app completion bash`.
+// Supported shells are bash, zsh, fish and powershell.
+func (c *Cli) EnableCompletion() *Cli {
+	command := c.NewSubCommand("completion", "Generate shell completion scripts")
+	command.LongDescription(`Outputs the completion script for the given shell: ` + strings.Join(completionShells, ", ") + `.
+To enable completion, add the following to your shell's configuration:
+
+  bash:       source <(` + c.Name() + ` completion bash)
+  zsh:        source <(` + c.Name() + ` completion zsh)
+  fish:       ` + c.Name() + ` completion fish | source
+  powershell: ` + c.Name() + ` completion powershell | Out-String | Invoke-Expression
+`)
+	command.Hidden()
+	command.internal = true
+	command.Action(func() error {
+		if command.flags.NArg() != 1 {
+			return fmt.Errorf("expected one of: %s", strings.Join(completionShells, ", "))
+		}
+		return c.WriteCompletion(c.Output(), command.flags.Arg(0))
+	})
+
+	// The completion scripts call `app __complete -- <words>` to resolve
+	// dynamic completions
+	dynamic := c.NewSubCommand("__complete", "Resolve dynamic completions")
+	dynamic.Hidden()
+	dynamic.internal = true
+	dynamic.Action(func() error {
+		for _, candidate := range c.complete(dynamic.flags.Args()) {
+			fmt.Fprintln(c.Output(), candidate)
+		}
+		return nil
+	})
+	return c
+}
+
+// CompleteFlag - Sets the function used to complete the value of the named
+// flag of the root command. See Command.CompleteFlag.
+func (c *Cli) CompleteFlag(name string, fn CompletionFunc) *Cli {
+	c.rootCommand.CompleteFlag(name, fn)
+	return c
+}
+
+// CompleteArgs - Sets the function used to complete the positional
+// arguments of the root command. See Command.CompleteArgs.
+func (c *Cli) CompleteArgs(fn CompletionFunc) *Cli {
+	c.rootCommand.CompleteArgs(fn)
+	return c
+}
+
+// CompleteFlag - Sets the function used to complete the value of the named
+// flag. The function is called at completion time with the flags typed so
+// far already parsed, so their variables may be used to tailor the result.
+func (c *Command) CompleteFlag(name string, fn CompletionFunc) *Command {
+	if c.flags.Lookup(name) == nil {
+		panic("CompleteFlag() requires flag '" + name + "' to be defined first")
+	}
+	c.flagDetail(name).completer = fn
+	return c
+}
+
+// CompleteArgs - Sets the function used to complete positional arguments
+// that have no completion function of their own
+func (c *Command) CompleteArgs(fn CompletionFunc) *Command {
+	c.argsCompleter = fn
+	return c
+}
+
+// CompleteArg - Sets the function used to complete the positional argument
+// at the given position. Positions start at 1, matching the `pos` tag.
+func (c *Command) CompleteArg(position int, fn CompletionFunc) *Command {
+	if position < 1 {
+		panic("CompleteArg() requires a position of 1 or greater")
+	}
+	c.argCompleters[position] = fn
+	return c
+}
+
+// complete returns the dynamic completion candidates for the last of the
+// given words, which are the arguments typed so far without the application
+// name. The flags typed before it are parsed so that completion functions
+// may use their values.
+func (c *Cli) complete(words []string) []string {
+	if len(words) == 0 {
+		return nil
+	}
+	prefix := words[len(words)-1]
+	words = words[:len(words)-1]
+
+	command := c.rootCommand
+	command.prepareCompletion()
+	var segment []string
+	pending := ""
+	terminated := false
+	for _, word := range words {
+		switch {
+		case pending != "":
+			pending = ""
+		case word == "--":
+			terminated = true
+		case terminated:
+		case strings.HasPrefix(word, "-"):
+			pending = command.valueFlagName(word)
+		case command.findSubCommand(word) != nil:
+			_ = command.parseFlags(segment)
+			command = command.findSubCommand(word)
+			command.prepareCompletion()
+			segment = nil
+			continue
+		}
+		segment = append(segment, word)
+	}
+	if pending != "" {
+		// Leave the flag out so its value isn't reported as missing
+		segment = segment[:len(segment)-1]
+	}
+	// Parse errors are expected as the command line is incomplete
+	_ = command.parseFlags(segment)
+	args := command.flags.Args()
+
+	var result []string
+	var completer CompletionFunc
+	switch {
+	case pending != "":
+		if detail := command.flagDetails[pending]; detail != nil {
+			completer = detail.completer
+			if completer == nil {
+				for _, choice := range detail.choices {
+					if strings.HasPrefix(choice, prefix) {
+						result = append(result, choice)
+					}
+				}
+			}
+		}
+	case terminated || !strings.HasPrefix(prefix, "-"):
+		if len(args) == 0 && !terminated {
+			for _, subcommand := range command.subCommands {
+				if !subcommand.isHidden() && strings.HasPrefix(subcommand.name, prefix) {
+					result = append(result, subcommand.name)
+				}
+			}
+		}
+		completer = command.argCompleters[len(args)+1]
+		if completer == nil {
+			completer = command.argsCompleter
+		}
+	}
+	if completer == nil {
+		return result
+	}
+	for _, candidate := range completer(prefix, args) {
+		if strings.HasPrefix(candidate, prefix) {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// prepareCompletion readies the command's flags for parsing a partial
+// command line, applying values from the config file and environment
+func (c *Command) prepareCompletion() {
+	c.resetFlagSet()
+	c.mergePersistentFlags()
+	_ = c.applyConfig()
+	_ = c.applyEnv()
+}
+
+// valueFlagName returns the name of the flag given by the word if it needs
+// a value that has not been given, EG: `--region` or `-r`, but not
+// `--region=eu` or a boolean flag
+func (c *Command) valueFlagName(word string) string {
+	if strings.Contains(word, "=") {
+		return ""
+	}
+	name := strings.TrimLeft(word, "-")
+	if name == "" {
+		return ""
+	}
+	if f := c.flags.Lookup(name); f != nil {
+		if isBoolFlag(f) {
+			return ""
+		}
+		return name
+	}
+	if strings.HasPrefix(word, "--") {
+		return ""
+	}
+	expanded, needsValue := c.expandShortFlagCluster(name)
+	if !needsValue {
+		return ""
+	}
+	return strings.TrimPrefix(expanded[len(expanded)-1], "--")
+}
+
+// WriteCompletion - Writes the completion script for the given shell to the
+// given writer. Supported shells are bash, zsh, fish and powershell.
+func (c *Cli) WriteCompletion(w io.Writer, shell string) error {
+	root := c.rootCommand.completionCommand("")
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion(c.Name(), root)
+	case "zsh":
+		script = zshCompletion(c.Name(), root)
+	case "fish":
+		script = fishCompletion(c.Name(), root)
+	case "powershell":
+		script = powershellCompletion(c.Name(), root)
+	default:
+		return fmt.Errorf("unsupported shell '%s'. Expected one of: %s", shell, strings.Join(completionShells, ", "))
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+// completionCommand builds the completion information for this command
+// and its visible subcommands
+func (c *Command) completionCommand(path string) *completionCommand {
+	c.mergePersistentFlags()
+	result := &completionCommand{
+		path:        path,
+		name:        c.name,
+		aliases:     c.aliases,
+		description: c.shortdescription,
+		positional:  len(c.positionalDetails) > 0 || (c.actionCallback != nil && len(c.subCommands) == 0),
+		dynamicArgs: c.argsCompleter != nil || len(c.argCompleters) > 0,
+	}
+	result.positional = result.positional || result.dynamicArgs
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if c.isFlagHidden(f.Name) {
+			return
+		}
+		detail := c.flagDetails[f.Name]
+		flag := &completionFlag{
+			name:        f.Name,
+			description: f.Usage,
+			takesValue:  !isBoolFlag(f),
+		}
+		if detail != nil {
+			flag.short = detail.short
+			flag.dynamic = detail.completer != nil && flag.takesValue
+			flag.choices = detail.choices
+		}
+		result.flags = append(result.flags, flag)
+	})
+	for _, subcommand := range c.subCommands {
+		if subcommand.isHidden() {
+			continue
+		}
+		subPath := subcommand.name
+		if path != "" {
+			subPath = path + " " + subcommand.name
+		}
+		result.subcommands = append(result.subcommands, subcommand.completionCommand(subPath))
+	}
+	return result
+}
+
+// walk calls fn for this command and all of its descendants
+func (cc *completionCommand) walk(fn func(*completionCommand)) {
+	fn(cc)
+	for _, subcommand := range cc.subcommands {
+		subcommand.walk(fn)
+	}
+}
+
+// subcommandNames returns the names of the visible subcommands
+func (cc *completionCommand) subcommandNames() []string {
+	var result []string
+	for _, subcommand := range cc.subcommands {
+		result = append(result, subcommand.name)
+	}
+	return result
+}
+
+// flagNames returns all the forms of the command's flags, sorted
+func (cc *completionCommand) flagNames() []string {
+	var result []string
+	for _, flag := range cc.flags {
+		result = append(result, flag.names()...)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// completionFunctionName returns a shell function name for the application
+func completionFunctionName(appName string) string {
+	return "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, appName)
+}
+
+// shellPathCases returns the `path:word` patterns used by the shell scripts
+// to work out the command path and which words are flag values.
+// The transitions map a pattern, using a subcommand's name or one of its
+// aliases, to the resulting command path. The dynamicFlags are the value
+// flags completed by the application and the choiceFlags map the patterns
+// of other flags restricted to a set of choices to those choices.
+func shellPathCases(root *completionCommand) (transitions [][2]string, valueFlags []string, dynamicFlags []string, choiceFlags [][2]string) {
+	root.walk(func(cc *completionCommand) {
+		for _, subcommand := range cc.subcommands {
+			transitions = append(transitions, [2]string{cc.path + ":" + subcommand.name, subcommand.path})
+			for _, alias := range subcommand.aliases {
+				transitions = append(transitions, [2]string{cc.path + ":" + alias, subcommand.path})
+			}
+		}
+		for _, flag := range cc.flags {
+			if !flag.takesValue {
+				continue
+			}
+			for _, name := range flag.names() {
+				valueFlags = append(valueFlags, cc.path+":"+name)
+				switch {
+				case flag.dynamic:
+					dynamicFlags = append(dynamicFlags, cc.path+":"+name)
+				case len(flag.choices) > 0:
+					choiceFlags = append(choiceFlags, [2]string{cc.path + ":" + name, strings.Join(flag.choices, " ")})
+				}
+			}
+		}
+	})
+	return transitions, valueFlags, dynamicFlags, choiceFlags
+}
+
+// argsMode returns how the shell scripts complete the command's positional
+// arguments: 0 for not at all, 1 for file paths and 2 for dynamically
+func (cc *completionCommand) argsMode() int {
+	switch {
+	case cc.dynamicArgs:
+		return 2
+	case cc.positional:
+		return 1
+	}
+	return 0
+}
+
+// shellQuote single quotes the given string for bash and zsh
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// shellList returns the given values single quoted and joined by spaces
+func shellList(values []string) string {
+	quoted := make([]string, len(values))
+	for index, value := range values {
+		quoted[index] = shellQuote(value)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quotePatterns returns the given patterns single quoted and joined by `|`
+func quotePatterns(patterns []string) string {
+	quoted := make([]string, len(patterns))
+	for index, pattern := range patterns {
+		quoted[index] = shellQuote(pattern)
+	}
+	return strings.Join(quoted, "|")
+}
+
+// bashCompletion generates the bash completion script
+func bashCompletion(appName string, root *completionCommand) string {
+	function := completionFunctionName(appName)
+	transitions, valueFlags, dynamicFlags, choiceFlags := shellPathCases(root)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s\n", appName)
+	fmt.Fprintf(&b, "# To enable, add the following to your ~/.bashrc:\n#   source <(%s completion bash)\n\n", appName)
+	fmt.Fprintf(&b, "%s_dynamic() {\n", function)
+	b.WriteString(`    local IFS=$'\n'
+    COMPREPLY=($("${COMP_WORDS[0]}" __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+
+`)
+	fmt.Fprintf(&b, "%s() {\n", function)
+	b.WriteString(`    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cmdpath="" word i skip=0
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        if [[ ${skip} -eq 1 ]]; then
+            skip=0
+            continue
+        fi
+        case "${cmdpath}:${word}" in
+`)
+	for _, transition := range transitions {
+		fmt.Fprintf(&b, "            %s) cmdpath=%s ;;\n", shellQuote(transition[0]), shellQuote(transition[1]))
+	}
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(&b, "            %s) skip=1 ;;\n", quotePatterns(valueFlags))
+	}
+	b.WriteString(`        esac
+    done
+
+`)
+	if len(valueFlags) > 0 {
+		b.WriteString(`    case "${cmdpath}:${prev}" in
+`)
+		if len(dynamicFlags) > 0 {
+			fmt.Fprintf(&b, "        %s)\n            %s_dynamic\n            return\n            ;;\n", quotePatterns(dynamicFlags), function)
+		}
+		for _, choiceFlag := range choiceFlags {
+			fmt.Fprintf(&b, "        %s)\n            COMPREPLY=($(compgen -W %s -- \"${cur}\"))\n            return\n            ;;\n", shellQuote(choiceFlag[0]), shellQuote(choiceFlag[1]))
+		}
+		fmt.Fprintf(&b, "        %s)\n", quotePatterns(valueFlags))
+		b.WriteString(`            COMPREPLY=($(compgen -f -- "${cur}"))
+            return
+            ;;
+    esac
+
+`)
+	}
+	b.WriteString(`    local commands="" flags="" args=0
+    case "${cmdpath}" in
+`)
+	root.walk(func(cc *completionCommand) {
+		fmt.Fprintf(&b, "        %s) commands=%s; flags=%s; args=%d ;;\n", shellQuote(cc.path), shellQuote(strings.Join(cc.subcommandNames(), " ")), shellQuote(strings.Join(cc.flagNames(), " ")), cc.argsMode())
+	})
+	b.WriteString(`    esac
+
+    if [[ "${cur}" == -* ]]; then
+        COMPREPLY=($(compgen -W "${flags}" -- "${cur}"))
+        return
+    fi
+    if [[ ${args} -eq 2 ]]; then
+`)
+	fmt.Fprintf(&b, "        %s_dynamic\n", function)
+	b.WriteString(`        return
+    fi
+    COMPREPLY=($(compgen -W "${commands}" -- "${cur}"))
+    if [[ ${args} -eq 1 ]]; then
+        COMPREPLY+=($(compgen -f -- "${cur}"))
+    fi
+}
+
+`)
+	fmt.Fprintf(&b, "complete -F %s %s\n", function, appName)
+	return b.String()
+}
+
+// zshCompletion generates the zsh completion script
+func zshCompletion(appName string, root *completionCommand) string {
+	function := completionFunctionName(appName)
+	transitions, valueFlags, dynamicFlags, choiceFlags := shellPathCases(root)
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n", appName)
+	fmt.Fprintf(&b, "# zsh completion for %s\n", appName)
+	fmt.Fprintf(&b, "# To enable, add the following to your ~/.zshrc:\n#   source <(%s completion zsh)\n\n", appName)
+	fmt.Fprintf(&b, "%s_dynamic() {\n", function)
+	b.WriteString(`    compadd -- ${(f)"$(${words[1]} __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)"}
+}
+
+`)
+	fmt.Fprintf(&b, "%s() {\n", function)
+	b.WriteString(`    local cur="${words[CURRENT]}"
+    local prev="${words[CURRENT-1]}"
+    local cmdpath="" word i skip=0
+    for ((i = 2; i < CURRENT; i++)); do
+        word="${words[i]}"
+        if (( skip )); then
+            skip=0
+            continue
+        fi
+        case "${cmdpath}:${word}" in
+`)
+	for _, transition := range transitions {
+		fmt.Fprintf(&b, "            %s) cmdpath=%s ;;\n", shellQuote(transition[0]), shellQuote(transition[1]))
+	}
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(&b, "            %s) skip=1 ;;\n", quotePatterns(valueFlags))
+	}
+	b.WriteString(`        esac
+    done
+
+`)
+	if len(valueFlags) > 0 {
+		b.WriteString(`    case "${cmdpath}:${prev}" in
+`)
+		if len(dynamicFlags) > 0 {
+			fmt.Fprintf(&b, "        %s)\n            %s_dynamic\n            return\n            ;;\n", quotePatterns(dynamicFlags), function)
+		}
+		for _, choiceFlag := range choiceFlags {
+			fmt.Fprintf(&b, "        %s)\n            compadd -- %s\n            return\n            ;;\n", shellQuote(choiceFlag[0]), shellList(strings.Fields(choiceFlag[1])))
+		}
+		fmt.Fprintf(&b, "        %s)\n", quotePatterns(valueFlags))
+		b.WriteString(`            _files
+            return
+            ;;
+    esac
+
+`)
+	}
+	b.WriteString(`    local -a commands flags
+    local args=0
+    case "${cmdpath}" in
+`)
+	root.walk(func(cc *completionCommand) {
+		fmt.Fprintf(&b, "        %s) commands=(%s); flags=(%s); args=%d ;;\n", shellQuote(cc.path), shellList(cc.subcommandNames()), shellList(cc.flagNames()), cc.argsMode())
+	})
+	b.WriteString(`    esac
+
+    if [[ "${cur}" == -* ]]; then
+        compadd -- "${flags[@]}"
+        return
+    fi
+    if (( args == 2 )); then
+`)
+	fmt.Fprintf(&b, "        %s_dynamic\n", function)
+	b.WriteString(`        return
+    fi
+    compadd -- "${commands[@]}"
+    if (( args == 1 )); then
+        _files
+    fi
+}
+
+`)
+	fmt.Fprintf(&b, "compdef %s %s\n", function, appName)
+	return b.String()
+}
+
+// fishQuote single quotes the given string for fish
+func fishQuote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// fishCompletion generates the fish completion script
+func fishCompletion(appName string, root *completionCommand) string {
+	function := "_" + completionFunctionName(appName) + "_cmdpath"
+	dynamic := "_" + completionFunctionName(appName) + "_dynamic"
+	transitions, valueFlags, _, _ := shellPathCases(root)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s\n", appName)
+	fmt.Fprintf(&b, "# To enable, run:\n#   %s completion fish > ~/.config/fish/completions/%s.fish\n\n", appName, appName)
+	fmt.Fprintf(&b, "function %s\n", dynamic)
+	b.WriteString(`    set -l current (commandline -ct)
+    set -l tokens (commandline -opc)
+    set -l command $tokens[1]
+    set -e tokens[1]
+    $command __complete -- $tokens "$current" 2>/dev/null
+end
+
+`)
+	fmt.Fprintf(&b, "function %s\n", function)
+	b.WriteString(`    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l cmdpath ""
+    set -l skip 0
+    for word in $tokens
+        if test $skip -eq 1
+            set skip 0
+            continue
+        end
+        switch "$cmdpath:$word"
+`)
+	for _, transition := range transitions {
+		fmt.Fprintf(&b, "            case %s\n                set cmdpath %s\n", fishQuote(transition[0]), fishQuote(transition[1]))
+	}
+	if len(valueFlags) > 0 {
+		quoted := make([]string, len(valueFlags))
+		for index, valueFlag := range valueFlags {
+			quoted[index] = fishQuote(valueFlag)
+		}
+		fmt.Fprintf(&b, "            case %s\n                set skip 1\n", strings.Join(quoted, " "))
+	}
+	b.WriteString(`        end
+    end
+    echo $cmdpath
+end
+
+`)
+	fmt.Fprintf(&b, "function %s_is\n", function)
+	fmt.Fprintf(&b, "    set -l current (%s)\n", function)
+	b.WriteString(`    test "$current" = "$argv[1]"
+end
+
+`)
+	fmt.Fprintf(&b, "complete -c %s -f\n", appName)
+	root.walk(func(cc *completionCommand) {
+		condition := fishQuote(function + "_is " + fishQuote(cc.path))
+		for _, subcommand := range cc.subcommands {
+			fmt.Fprintf(&b, "complete -c %s -n %s -a %s -d %s\n", appName, condition, fishQuote(subcommand.name), fishQuote(subcommand.description))
+		}
+		for _, flag := range cc.flags {
+			fmt.Fprintf(&b, "complete -c %s -n %s -l %s", appName, condition, fishQuote(flag.name))
+			if flag.short != "" {
+				fmt.Fprintf(&b, " -s %s", fishQuote(flag.short))
+			}
+			switch {
+			case flag.dynamic:
+				fmt.Fprintf(&b, " -x -a %s", fishQuote("("+dynamic+")"))
+			case flag.takesValue && len(flag.choices) > 0:
+				fmt.Fprintf(&b, " -x -a %s", fishQuote(strings.Join(flag.choices, " ")))
+			case flag.takesValue:
+				b.WriteString(" -r -F")
+			}
+			fmt.Fprintf(&b, " -d %s\n", fishQuote(flag.description))
+		}
+		switch cc.argsMode() {
+		case 2:
+			fmt.Fprintf(&b, "complete -c %s -n %s -a %s\n", appName, condition, fishQuote("("+dynamic+")"))
+		case 1:
+			fmt.Fprintf(&b, "complete -c %s -n %s -F\n", appName, condition)
+		}
+	})
+	return b.String()
+}
+
+// powershellQuote single quotes the given string for PowerShell
+func powershellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// powershellList returns the given values as a PowerShell array
+func powershellList(values []string) string {
+	quoted := make([]string, len(values))
+	for index, value := range values {
+		quoted[index] = powershellQuote(value)
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+// powershellCompletion generates the PowerShell completion script
+func powershellCompletion(appName string, root *completionCommand) string {
+	transitions, valueFlags, dynamicFlags, choiceFlags := shellPathCases(root)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# powershell completion for %s\n", appName)
+	fmt.Fprintf(&b, "# To enable, add the following to your profile:\n#   %s completion powershell | Out-String | Invoke-Expression\n\n", appName)
+	fmt.Fprintf(&b, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", powershellQuote(appName))
+	b.WriteString(`    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $elements = @($commandAst.CommandElements | Where-Object { $_.Extent.EndOffset -le $cursorPosition } | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -ne '' -and $elements.Count -gt 1) {
+        $elements = $elements[0..($elements.Count - 2)]
+    }
+
+    $cmdpath = ''
+    $prev = ''
+    $skip = $false
+    foreach ($word in ($elements | Select-Object -Skip 1)) {
+        $prev = $word
+        if ($skip) {
+            $skip = $false
+            continue
+        }
+`)
+	// PowerShell does not allow an empty switch statement
+	if len(transitions)+len(valueFlags) > 0 {
+		b.WriteString(`        switch -CaseSensitive ("${cmdpath}:${word}") {
+`)
+		for _, transition := range transitions {
+			fmt.Fprintf(&b, "            %s { $cmdpath = %s }\n", powershellQuote(transition[0]), powershellQuote(transition[1]))
+		}
+		for _, valueFlag := range valueFlags {
+			fmt.Fprintf(&b, "            %s { $skip = $true }\n", powershellQuote(valueFlag))
+		}
+		b.WriteString(`        }
+`)
+	}
+	b.WriteString(`    }
+
+    $commands = @()
+    $flags = @()
+    $candidates = @()
+    $argsMode = 0
+    $files = $false
+    $dynamic = $false
+    if ($skip) {
+        $files = $true
+`)
+	if len(dynamicFlags)+len(choiceFlags) > 0 {
+		b.WriteString(`        switch -CaseSensitive ("${cmdpath}:${prev}") {
+`)
+		for _, dynamicFlag := range dynamicFlags {
+			fmt.Fprintf(&b, "            %s { $dynamic = $true }\n", powershellQuote(dynamicFlag))
+		}
+		for _, choiceFlag := range choiceFlags {
+			fmt.Fprintf(&b, "            %s { $candidates = %s; $files = $false }\n", powershellQuote(choiceFlag[0]), powershellList(strings.Fields(choiceFlag[1])))
+		}
+		b.WriteString(`        }
+`)
+	}
+	b.WriteString(`    } else {
+        switch -CaseSensitive ($cmdpath) {
+`)
+	root.walk(func(cc *completionCommand) {
+		fmt.Fprintf(&b, "            %s { $commands = %s; $flags = %s; $argsMode = %d }\n", powershellQuote(cc.path), powershellList(cc.subcommandNames()), powershellList(cc.flagNames()), cc.argsMode())
+	})
+	b.WriteString(`        }
+        if ($wordToComplete -like '-*') {
+            $candidates = $flags
+        } else {
+            $candidates = $commands
+            $files = $argsMode -eq 1
+            $dynamic = $argsMode -eq 2
+        }
+    }
+    if ($dynamic) {
+        $candidates = @(& $elements[0] __complete -- @($elements | Select-Object -Skip 1) $wordToComplete 2>$null)
+        $files = $false
+    }
+
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+    if ($files) {
+        Get-ChildItem -Path "$wordToComplete*" -ErrorAction SilentlyContinue | ForEach-Object {
+            $item = $_.Name
+            if ($wordToComplete -match '[\\/]') {
+                $item = Join-Path (Split-Path $wordToComplete) $_.Name
+            }
+            [System.Management.Automation.CompletionResult]::new($item, $item, 'ProviderItem', $item)
+        }
+    }
+}
+`)
+	return b.String()
+}
@@ -0,0 +1,295 @@
+package clir
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCompletionCli() *Cli {
+	c := NewCli("app", "description", "0")
+	var config string
+	var verbose bool
+	c.PersistentFlags().StringFlag("config", "The config file", &config)
+	c.BoolFlag("verbose", "Show more output", &verbose).ShortFlag("verbose", "v")
+	db := c.NewSubCommand("db", "Database commands")
+	migrate := db.NewSubCommand("migrate", "Migrate the database")
+	var dryRun bool
+	migrate.BoolFlag("dry-run", "Don't apply changes", &dryRun)
+	migrate.Action(func() error { return nil })
+	secret := c.NewSubCommand("secret", "Secret commands")
+	secret.Hidden()
+	c.EnableCompletion()
+	return c
+}
+
+func TestCli_WriteCompletion(t *testing.T) {
+	c := newCompletionCli()
+	for _, shell := range completionShells {
+		var buffer bytes.Buffer
+		if err := c.WriteCompletion(&buffer, shell); err != nil {
+			t.Fatalf("%s: expected no error, got %v", shell, err)
+		}
+		script := buffer.String()
+		for _, expected := range []string{"migrate", "config", "dry-run", "verbose"} {
+			if !strings.Contains(script, expected) {
+				t.Errorf("%s: expected script to contain %q", shell, expected)
+			}
+		}
+		for _, unexpected := range []string{"secret", "completion"} {
+			if strings.Contains(strings.SplitN(script, "\n\n", 2)[1], unexpected) {
+				t.Errorf("%s: expected script not to contain hidden command %q", shell, unexpected)
+			}
+		}
+	}
+
+	if err := c.WriteCompletion(&bytes.Buffer{}, "cmd"); err == nil {
+		t.Errorf("expected error for unsupported shell")
+	}
+}
+
+func TestCli_CompletionCommand(t *testing.T) {
+	c := newCompletionCli()
+	if err := c.Run("completion"); err == nil {
+		t.Errorf("expected error when no shell is given")
+	}
+	if err := c.Run("completion", "cmd"); err == nil {
+		t.Errorf("expected error for unsupported shell")
+	}
+}
+
+func TestCli_CompletionRequiredFlag(t *testing.T) {
+	c := newCompletionCli()
+	var token string
+	c.PersistentFlags().StringFlag("token", "The API token", &token).Required("token")
+	preRuns := 0
+	c.PreRun(func(*Cli) error {
+		preRuns++
+		return nil
+	})
+	c.EnableManPages()
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if err := c.Run("completion", "bash"); err != nil || !strings.Contains(output.String(), "__complete") {
+		t.Errorf("expected the completion script, got %v", err)
+	}
+	output.Reset()
+	if err := c.Run("__complete", "--", "d"); err != nil || output.String() != "db\n" {
+		t.Errorf("expected completions, got %v, %q", err, output.String())
+	}
+	if err := c.Run("gen-man", t.TempDir()); err != nil {
+		t.Errorf("expected man pages to be written, got %v", err)
+	}
+	if preRuns != 0 {
+		t.Errorf("expected PreRun not to be called by internal commands, called %d times", preRuns)
+	}
+
+	// Other commands still require the flag
+	var missing *MissingRequiredError
+	if err := c.Run("db", "migrate"); !errors.As(err, &missing) || preRuns != 1 {
+		t.Errorf("expected a missing required flag error, got %v", err)
+	}
+}
+
+func TestCli_CompletionAfterFlags(t *testing.T) {
+	c := NewCli("app", "description", "0").ConfigFile("config")
+	var verbose bool
+	var token string
+	c.BoolFlag("verbose", "Show more output", &verbose).ShortFlag("verbose", "v")
+	c.PersistentFlags().StringFlag("token", "The API token", &token).Required("token")
+	c.NewSubCommand("db", "Database commands").Action(func() error { return nil })
+	c.EnableCompletion()
+	runs := 0
+	c.PreRun(func(*Cli) error {
+		runs++
+		return nil
+	})
+	c.PostRun(func(*Cli) error {
+		runs++
+		return nil
+	})
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if err := c.Run("--config", missing, "completion", "bash"); err != nil || !strings.Contains(output.String(), "__complete") {
+		t.Errorf("expected the completion script, got %v", err)
+	}
+	output.Reset()
+	if err := c.Run("-v", "__complete", "--", "d"); err != nil || output.String() != "db\n" {
+		t.Errorf("expected completions, got %v, %q", err, output.String())
+	}
+	if runs != 0 {
+		t.Errorf("expected PreRun and PostRun not to be called by internal commands, called %d times", runs)
+	}
+
+	// Other commands still load the config file
+	if err := c.Run("--config", missing, "db"); err == nil || !strings.Contains(err.Error(), "unable to read config file") {
+		t.Errorf("expected the config file to be read, got %v", err)
+	}
+}
+
+func TestCli_BashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	var buffer bytes.Buffer
+	if err := newCompletionCli().WriteCompletion(&buffer, "bash"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	script := filepath.Join(t.TempDir(), "app.bash")
+	if err := os.WriteFile(script, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	complete := func(words ...string) string {
+		command := `source "$1"; shift; COMP_WORDS=("$@"); COMP_CWORD=$(($# - 1)); _app; echo "${COMPREPLY[*]}"`
+		output, err := exec.Command(bash, append([]string{"-c", command, "bash", script}, words...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("completion failed: %v: %s", err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	tests := []struct {
+		words    []string
+		expected string
+	}{
+		{[]string{"app", ""}, "db"},
+		{[]string{"app", "--"}, "--config --help --verbose"},
+		{[]string{"app", "-v", "d"}, "db"},
+		{[]string{"app", "db", ""}, "migrate"},
+		{[]string{"app", "--config", "x.json", "db", "migrate", "--d"}, "--dry-run"},
+		{[]string{"app", "db", "migrate", "--"}, "--config --dry-run --help"},
+	}
+	for _, test := range tests {
+		if actual := complete(test.words...); actual != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.words, test.expected, actual)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh"} {
+		path, err := exec.LookPath(shell)
+		if err != nil {
+			continue
+		}
+		for _, value := range []string{"plain", "it's", "$HOME", "`date`", `a\b`, `"quoted"`, "tab\there"} {
+			output, err := exec.Command(path, "-c", "printf %s "+shellQuote(value)).CombinedOutput()
+			if err != nil || string(output) != value {
+				t.Errorf("%s: expected %q, got %v, %q", shell, value, err, output)
+			}
+		}
+	}
+}
+
+func newDynamicCompletionCli() *Cli {
+	c := NewCli("app", "description", "0")
+	var context string
+	c.PersistentFlags().StringFlag("context", "The cluster context", &context).
+		CompleteFlag("context", func(prefix string, args []string) []string {
+			return []string{"dev", "prod"}
+		})
+	var namespace string
+	get := c.NewSubCommand("get", "Get a resource")
+	get.StringFlag("namespace", "The namespace", &namespace).
+		ShortFlag("namespace", "n").
+		CompleteFlag("namespace", func(prefix string, args []string) []string {
+			// The flags typed so far are visible
+			return []string{context + "-default", context + "-system"}
+		})
+	get.CompleteArg(1, func(prefix string, args []string) []string {
+		return []string{"pods", "services"}
+	})
+	get.CompleteArgs(func(prefix string, args []string) []string {
+		return []string{args[0] + "/one", args[0] + "/two"}
+	})
+	get.Action(func() error { return nil })
+	c.NewSubCommand("generate", "Generate things")
+	c.EnableCompletion()
+	return c
+}
+
+func TestCli_Complete(t *testing.T) {
+	c := newDynamicCompletionCli()
+	tests := []struct {
+		words    []string
+		expected []string
+	}{
+		{[]string{"g"}, []string{"get", "generate"}},
+		{[]string{"--context", ""}, []string{"dev", "prod"}},
+		{[]string{"--context", "p"}, []string{"prod"}},
+		{[]string{"--context", "dev", "get", "--namespace", ""}, []string{"dev-default", "dev-system"}},
+		{[]string{"get", "--context=prod", "-n", "prod-s"}, []string{"prod-system"}},
+		{[]string{"get", ""}, []string{"pods", "services"}},
+		{[]string{"get", "-n", "x", "pods", ""}, []string{"pods/one", "pods/two"}},
+		{[]string{"get", "--"}, nil},
+		{[]string{"generate", ""}, nil},
+	}
+	for _, test := range tests {
+		actual := c.complete(test.words)
+		if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%v: expected %v, got %v", test.words, test.expected, actual)
+		}
+	}
+
+	if err := c.Run("__complete", "--", "get", "--namespace", ""); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestCommand_CompleteFlagUndefined(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic for undefined flag")
+		}
+	}()
+	NewCli("app", "description", "0").CompleteFlag("missing", nil)
+}
+
+func TestCli_BashDynamicCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	var buffer bytes.Buffer
+	if err := newDynamicCompletionCli().WriteCompletion(&buffer, "bash"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	script := filepath.Join(t.TempDir(), "app.bash")
+	if err := os.WriteFile(script, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stub the application so the arguments given to __complete are echoed
+	complete := func(words ...string) string {
+		command := `source "$1"; shift; app() { printf '%s\n' "$*"; }; COMP_WORDS=("$@"); COMP_CWORD=$(($# - 1)); _app; echo "${COMPREPLY[*]}"`
+		output, err := exec.Command(bash, append([]string{"-c", command, "bash", script}, words...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("completion failed: %v: %s", err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	tests := []struct {
+		words    []string
+		expected string
+	}{
+		{[]string{"app", "--context", "d"}, "__complete -- --context d"},
+		{[]string{"app", "get", "-n", ""}, "__complete -- get -n "},
+		{[]string{"app", "get", "p"}, "__complete -- get p"},
+		{[]string{"app", "g"}, "get generate"},
+	}
+	for _, test := range tests {
+		if actual := complete(test.words...); actual != strings.TrimSpace(test.expected) {
+			t.Errorf("%v: expected %q, got %q", test.words, test.expected, actual)
+		}
+	}
+}
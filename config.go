@@ -0,0 +1,499 @@
+package clir
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configExtensions are the supported config file extensions, in the order
+// they are searched for by DefaultConfigPaths
+var configExtensions = []string{".json", ".toml", ".yaml", ".yml", ".ini"}
+
+// DefaultConfigPaths - Returns the default search paths for the config file
+// of the given application, EG: `$XDG_CONFIG_HOME/<app>/config.json`
+func DefaultConfigPaths(appName string) []string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for _, ext := range configExtensions {
+		result = append(result, filepath.Join(dir, appName, "config"+ext))
+	}
+	return result
+}
+
+// configFile holds the details of how the config file is located and
+// the values loaded from it
+type configFile struct {
+	flagName    string
+	searchPaths []string
+	path        string
+	values      map[string][]string
+}
+
+// load locates and parses the config file. A path given by the config
+// flag must exist, otherwise the first search path that exists is used.
+func (cf *configFile) load(root *Command, args []string) error {
+	cf.path = ""
+	cf.values = nil
+
+	path := cf.flagValue(root, args)
+	if path == "" {
+		for _, searchPath := range cf.searchPaths {
+			if _, err := os.Stat(searchPath); err == nil {
+				path = searchPath
+				break
+			}
+		}
+	}
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %v", err)
+	}
+	values, err := parseConfig(path, data)
+	if err != nil {
+		return fmt.Errorf("unable to parse config file %s: %v", path, err)
+	}
+	cf.path = path
+	cf.values = values
+	return cf.checkKeys(root)
+}
+
+// checkKeys returns an error listing the keys in the config file that do
+// not match a flag of any command. Keys nested under a map flag, EG:
+// `deploy.label.env`, are the map's keys.
+func (cf *configFile) checkKeys(root *Command) error {
+	known := make(map[string]bool)
+	var maps []string
+	root.walk(func(command *Command) {
+		for _, flags := range []*Command{command, command.persistentFlags} {
+			if flags == nil || flags.flags == nil {
+				continue
+			}
+			flags.flags.VisitAll(func(f *flag.Flag) {
+				if flags == command && command.inheritedFlags[f.Name] {
+					return
+				}
+				key := command.configKey(f.Name)
+				known[key] = true
+				if _, ok := underlyingValue(f).(*mapValue); ok {
+					maps = append(maps, key)
+				}
+			})
+		}
+	})
+	var unknown []string
+	for key := range cf.values {
+		if !known[key] && !hasMapPrefix(key, maps) {
+			unknown = append(unknown, "'"+key+"'")
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	noun := "key"
+	if len(unknown) > 1 {
+		noun = "keys"
+	}
+	return fmt.Errorf("unknown %s %s in config file %s", noun, strings.Join(unknown, ", "), cf.path)
+}
+
+// hasMapPrefix returns true if the key is nested under one of the given
+// map flag keys
+func hasMapPrefix(key string, maps []string) bool {
+	for _, prefix := range maps {
+		if strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// mapPairs returns the values nested under the given map flag key as
+// `key=value` pairs, sorted by key
+func (cf *configFile) mapPairs(prefix string) []string {
+	var keys []string
+	for key := range cf.values {
+		if strings.HasPrefix(key, prefix+".") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var result []string
+	for _, key := range keys {
+		for _, value := range cf.values[key] {
+			result = append(result, strings.TrimPrefix(key, prefix+".")+"="+value)
+		}
+	}
+	return result
+}
+
+// flagValue returns the config file path given on the command line or
+// through the environment variable bound to the config flag.
+// The arguments are scanned as config values must be applied before the
+// command line is parsed. Subcommands are followed in the same way as when
+// the command line is run, so only flags that resolve to the config flag
+// are used, rather than a subcommand's flag of the same name or the value
+// of another flag.
+func (cf *configFile) flagValue(root *Command, args []string) string {
+	if cf.flagName == "" {
+		return ""
+	}
+	persistent := root.PersistentFlags()
+	configFlag := persistent.flags.Lookup(cf.flagName)
+	command := root
+	command.mergePersistentFlags()
+	positional := false
+	for index := 0; index < len(args); index++ {
+		arg := args[index]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			// Only the first argument after a command's leading flags
+			// may be a subcommand
+			if subcommand := command.findSubCommand(arg); subcommand != nil && !positional {
+				command = subcommand
+				command.mergePersistentFlags()
+				continue
+			}
+			positional = true
+			continue
+		}
+		expanded := command.expandShortFlags([]string{arg})
+		name, value, hasValue := strings.Cut(strings.TrimLeft(expanded[len(expanded)-1], "-"), "=")
+		f := command.flags.Lookup(name)
+		if f == nil || isBoolFlag(f) {
+			continue
+		}
+		isConfigFlag := configFlag != nil && f.Value == configFlag.Value
+		if hasValue {
+			if isConfigFlag {
+				return value
+			}
+			continue
+		}
+		if isConfigFlag && index+1 < len(args) {
+			return args[index+1]
+		}
+		// Skip over the flag's value
+		index++
+	}
+	if envVar := root.envVar(cf.flagName, persistent.flagDetails[cf.flagName]); envVar != "" {
+		return os.Getenv(envVar)
+	}
+	return ""
+}
+
+// configKey returns the key used to look up the given flag of this command
+// in the config file. Keys are the command path, without the application
+// name, followed by the flag name, EG: `db.migrate.dry-run`
+func (c *Command) configKey(name string) string {
+	parts := append(strings.Fields(c.commandPath)[1:], name)
+	return strings.Join(parts, ".")
+}
+
+// applyConfig sets the flags defined by this command from the values in
+// the config file. Environment variables and the command line are applied
+// afterwards so take precedence.
+func (c *Command) applyConfig() error {
+	config := c.app.config
+	if config == nil || config.values == nil {
+		return nil
+	}
+	var err error
+	c.visitOwnFlags(func(f *flag.Flag, _ *flagDetail) {
+		if err != nil || f.Name == "help" {
+			return
+		}
+		key := c.configKey(f.Name)
+		values := config.values[key]
+		if _, ok := underlyingValue(f).(*mapValue); ok {
+			values = append(values, config.mapPairs(key)...)
+		}
+		for _, value := range values {
+			if setErr := c.setFlag(f.Name, value, sourceConfig); setErr != nil {
+				err = &InvalidValueError{
+					Command: c.commandPath,
+					Flag:    f.Name,
+					Value:   value,
+					Source:  key + " in config file " + config.path,
+					Err:     setErr,
+				}
+				return
+			}
+		}
+	})
+	return err
+}
+
+// parseConfig parses the given config file data based on the file extension.
+// The result maps dotted keys, EG: `deploy.region` to their values.
+func parseConfig(path string, data []byte) (map[string][]string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return parseJSONConfig(data)
+	case ".toml":
+		return parseTOMLConfig(data)
+	case ".yaml", ".yml":
+		return parseYAMLConfig(data)
+	case ".ini", ".conf", ".cfg":
+		return parseINIConfig(data)
+	default:
+		return nil, fmt.Errorf("unsupported config file type '%s'", filepath.Ext(path))
+	}
+}
+
+// parseJSONConfig parses a JSON config file. Nested objects represent
+// subcommands.
+func parseJSONConfig(data []byte) (map[string][]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var root map[string]interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+	result := make(map[string][]string)
+	var flatten func(prefix string, values map[string]interface{}) error
+	flatten = func(prefix string, values map[string]interface{}) error {
+		for key, value := range values {
+			switch value := value.(type) {
+			case nil:
+			case map[string]interface{}:
+				if err := flatten(prefix+key+".", value); err != nil {
+					return err
+				}
+			case []interface{}:
+				for _, item := range value {
+					if _, ok := item.(map[string]interface{}); ok {
+						return fmt.Errorf("unsupported value for key '%s'", prefix+key)
+					}
+					result[prefix+key] = append(result[prefix+key], fmt.Sprint(item))
+				}
+			default:
+				result[prefix+key] = append(result[prefix+key], fmt.Sprint(value))
+			}
+		}
+		return nil
+	}
+	if err := flatten("", root); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseTOMLConfig parses the subset of TOML used for configuration:
+// tables, `key = value` pairs, strings, numbers, booleans and single
+// line arrays. Tables represent subcommands, EG: `[db.migrate]`.
+func parseTOMLConfig(data []byte) (map[string][]string, error) {
+	result := make(map[string][]string)
+	prefix := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(stripComment(scanner.Text(), "#"))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table '%s'", lineNumber, line)
+			}
+			prefix = strings.TrimSpace(line[1:len(line)-1]) + "."
+			continue
+		}
+		equals := strings.Index(line, "=")
+		if equals < 0 {
+			return nil, fmt.Errorf("line %d: expected 'key = value'", lineNumber)
+		}
+		key := unquoteConfigValue(strings.TrimSpace(line[:equals]))
+		values, err := parseConfigValue(strings.TrimSpace(line[equals+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		result[prefix+key] = append(result[prefix+key], values...)
+	}
+	return result, scanner.Err()
+}
+
+// parseINIConfig parses an INI config file. Sections represent subcommands,
+// EG: `[db.migrate]` or `[db migrate]`. Repeated keys provide multiple
+// values for slice flags.
+func parseINIConfig(data []byte) (map[string][]string, error) {
+	result := make(map[string][]string)
+	prefix := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section '%s'", lineNumber, line)
+			}
+			section := strings.Fields(line[1 : len(line)-1])
+			prefix = strings.Join(section, ".") + "."
+			continue
+		}
+		separator := strings.IndexAny(line, "=:")
+		if separator < 0 {
+			return nil, fmt.Errorf("line %d: expected 'key = value'", lineNumber)
+		}
+		key := strings.TrimSpace(line[:separator])
+		value := unquoteConfigValue(strings.TrimSpace(line[separator+1:]))
+		result[prefix+key] = append(result[prefix+key], value)
+	}
+	return result, scanner.Err()
+}
+
+// parseYAMLConfig parses the subset of YAML used for configuration:
+// nested mappings, scalars, block sequences (`- item`) and flow
+// sequences (`[a, b]`). Nested mappings represent subcommands.
+func parseYAMLConfig(data []byte) (map[string][]string, error) {
+	type level struct {
+		indent int
+		prefix string
+	}
+	result := make(map[string][]string)
+	levels := []level{{indent: -1}}
+	lastKey := ""
+	lastIndent := -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text := stripComment(scanner.Text(), "#")
+		line := strings.TrimSpace(text)
+		if line == "" || line == "---" {
+			continue
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+
+		// Sequence items belong to the last key without a value
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			if lastKey == "" || indent < lastIndent {
+				return nil, fmt.Errorf("line %d: unexpected sequence item", lineNumber)
+			}
+			result[lastKey] = append(result[lastKey], unquoteConfigValue(strings.TrimSpace(line[1:])))
+			continue
+		}
+
+		for indent <= levels[len(levels)-1].indent {
+			levels = levels[:len(levels)-1]
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: expected 'key: value'", lineNumber)
+		}
+		key := levels[len(levels)-1].prefix + unquoteConfigValue(strings.TrimSpace(line[:colon]))
+		value := strings.TrimSpace(line[colon+1:])
+		if value == "" {
+			// Either a nested mapping or a block sequence follows
+			levels = append(levels, level{indent: indent, prefix: key + "."})
+			lastKey = key
+			lastIndent = indent
+			continue
+		}
+		lastKey = ""
+		values, err := parseConfigValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		result[key] = append(result[key], values...)
+	}
+	return result, scanner.Err()
+}
+
+// parseConfigValue parses a scalar or a single line array, EG: `[1, 2]`
+func parseConfigValue(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") {
+		return []string{unquoteConfigValue(value)}, nil
+	}
+	if !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("unterminated array '%s'", value)
+	}
+	var result []string
+	for _, item := range splitConfigArray(value[1 : len(value)-1]) {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, unquoteConfigValue(item))
+		}
+	}
+	return result, nil
+}
+
+// splitConfigArray splits the items of an array on commas that are not
+// within quotes
+func splitConfigArray(value string) []string {
+	var result []string
+	var quote rune
+	start := 0
+	for index, char := range value {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == ',':
+			result = append(result, value[start:index])
+			start = index + 1
+		}
+	}
+	return append(result, value[start:])
+}
+
+// unquoteConfigValue removes the quotes from a quoted string value
+func unquoteConfigValue(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	switch {
+	case value[0] == '"' && value[len(value)-1] == '"':
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+		return value[1 : len(value)-1]
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// stripComment removes a trailing comment from the line, ignoring comment
+// markers that are within quotes
+func stripComment(line string, marker string) string {
+	var quote rune
+	for index, char := range line {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case strings.HasPrefix(line[index:], marker):
+			return line[:index]
+		}
+	}
+	return line
+}
@@ -0,0 +1,328 @@
+package clir
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type ConfigDeployFlags struct {
+	Region string   `description:"The region" default:"us"`
+	Port   int      `description:"The port" default:"80"`
+	DryRun bool     `name:"dry-run" description:"Don't change anything"`
+	Tags   []string `description:"The tags"`
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCli_ConfigFileFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.json", `{
+	"verbose": true,
+	"deploy": {"region": "eu", "port": 8080, "dry-run": true, "tags": ["a", "b"]}
+}`},
+		{"config.toml", `
+verbose = true # comment
+
+[deploy]
+region = "eu"
+port = 8080
+dry-run = true
+tags = ["a", "b"]
+`},
+		{"config.yaml", `
+# comment
+verbose: true
+deploy:
+  region: "eu"
+  port: 8080
+  dry-run: true
+  tags:
+    - a
+    - b
+`},
+		{"config.ini", `
+; comment
+verbose = true
+
+[deploy]
+region = eu
+port = 8080
+dry-run = true
+tags = a
+tags = b
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.name, tt.content)
+			c := NewCli("test", "description", "0").ConfigFile("config")
+			var verbose bool
+			c.BoolFlag("verbose", "Verbose output", &verbose)
+			flags := &ConfigDeployFlags{}
+			c.NewSubCommand("deploy", "deploy the app").AddFlags(flags).Action(func() error { return nil })
+
+			if e := c.Run("--config", path, "deploy"); e != nil {
+				t.Fatalf("expected no error, got %v", e)
+			}
+			if !verbose {
+				t.Errorf("expected verbose to be set from config")
+			}
+			expected := &ConfigDeployFlags{Region: "eu", Port: 8080, DryRun: true, Tags: []string{"a", "b"}}
+			if !reflect.DeepEqual(flags, expected) {
+				t.Errorf("expected %+v, got %+v", expected, flags)
+			}
+		})
+	}
+}
+
+func TestCli_ConfigFilePrecedence(t *testing.T) {
+	path := writeConfig(t, "config.json", `{"deploy": {"region": "config", "port": 1000}}`)
+	t.Setenv("APP_PORT", "2000")
+
+	c := NewCli("test", "description", "0").ConfigFile("", path)
+	flags := &ConfigDeployFlags{}
+	deploy := c.NewSubCommand("deploy", "deploy the app").AddFlags(flags).EnvVar("port", "APP_PORT")
+	deploy.Action(func() error { return nil })
+
+	if e := c.Run("deploy", "--dry-run"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Region != "config" {
+		t.Errorf("expected region from config file, got %q", flags.Region)
+	}
+	if flags.Port != 2000 {
+		t.Errorf("expected port from environment, got %v", flags.Port)
+	}
+
+	if e := c.Run("deploy", "--region", "cli"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Region != "cli" {
+		t.Errorf("expected region from command line, got %q", flags.Region)
+	}
+}
+
+func TestCli_ConfigFileSlicePrecedence(t *testing.T) {
+	path := writeConfig(t, "config.json", `{"deploy": {"tags": ["config1", "config2"]}}`)
+	c := NewCli("test", "description", "0").ConfigFile("", path)
+	tags := []string{"default"}
+	deploy := c.NewSubCommand("deploy", "deploy the app")
+	deploy.StringsFlag("tags", "The tags", &tags).EnvVar("tags", "APP_TAGS")
+	deploy.Action(func() error { return nil })
+
+	tests := []struct {
+		env      string
+		args     []string
+		expected []string
+	}{
+		{"", []string{"deploy"}, []string{"config1", "config2"}},
+		{"env", []string{"deploy"}, []string{"env"}},
+		{"env", []string{"deploy", "--tags", "cli1", "--tags", "cli2"}, []string{"cli1", "cli2"}},
+		{"", []string{"deploy", "--tags", "cli"}, []string{"cli"}},
+	}
+	for _, test := range tests {
+		c.ResetFlags()
+		os.Unsetenv("APP_TAGS")
+		if test.env != "" {
+			t.Setenv("APP_TAGS", test.env)
+		}
+		if e := c.Run(test.args...); e != nil {
+			t.Fatalf("%v: expected no error, got %v", test.args, e)
+		}
+		if !reflect.DeepEqual(tags, test.expected) {
+			t.Errorf("env %q, %v: expected %v, got %v", test.env, test.args, test.expected, tags)
+		}
+	}
+}
+
+func TestCli_ConfigFileCustomValuePrecedence(t *testing.T) {
+	path := writeConfig(t, "config.json", `{"release": {"label": ["config1", "config2"]}}`)
+	c := NewCli("test", "description", "0").ConfigFile("", path)
+	var flags *releaseFlags
+	c.NewSubCommandFunction("release", "Release a version", func(f *releaseFlags) error {
+		flags = f
+		return nil
+	})
+
+	tests := []struct {
+		args     []string
+		expected labels
+	}{
+		{[]string{"release", "v1.0.0"}, labels{"config1", "config2"}},
+		{[]string{"release", "--label", "cli1", "--label", "cli2", "v1.0.0"}, labels{"cli1", "cli2"}},
+	}
+	for _, test := range tests {
+		c.ResetFlags()
+		if e := c.Run(test.args...); e != nil {
+			t.Fatalf("%v: expected no error, got %v", test.args, e)
+		}
+		if !reflect.DeepEqual(flags.Labels, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.args, test.expected, flags.Labels)
+		}
+	}
+}
+
+func TestCli_ConfigFileMapFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.json", `{"deploy": {"label": {"env": "prod", "team": "core"}, "limit": "cpu=2"}}`},
+		{"config.yaml", `
+deploy:
+  label:
+    env: prod
+    team: core
+  limit: cpu=2
+`},
+		{"config.toml", `
+[deploy]
+limit = "cpu=2"
+
+[deploy.label]
+env = "prod"
+team = "core"
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.name, tt.content)
+			c := NewCli("test", "description", "0").ConfigFile("", path)
+			flags := &struct {
+				Labels map[string]string `name:"label" default:"env=dev"`
+				Limits map[string]int    `name:"limit"`
+			}{}
+			c.NewSubCommand("deploy", "deploy the app").AddFlags(flags).Action(func() error { return nil })
+
+			if e := c.Run("deploy"); e != nil {
+				t.Fatalf("expected no error, got %v", e)
+			}
+			if !reflect.DeepEqual(flags.Labels, map[string]string{"env": "prod", "team": "core"}) {
+				t.Errorf("unexpected labels: %v", flags.Labels)
+			}
+			if !reflect.DeepEqual(flags.Limits, map[string]int{"cpu": 2}) {
+				t.Errorf("unexpected limits: %v", flags.Limits)
+			}
+		})
+	}
+}
+
+func TestCli_ConfigFileUnknownKeys(t *testing.T) {
+	path := writeConfig(t, "config.json", `{"region": "eu", "deploy": {"region": "us", "zone": "a"}}`)
+	c := NewCli("test", "description", "0").ConfigFile("", path)
+	flags := &ConfigDeployFlags{}
+	c.NewSubCommand("deploy", "deploy the app").AddFlags(flags).Action(func() error { return nil })
+
+	e := c.Run("deploy")
+	expected := "unknown keys 'deploy.zone', 'region' in config file " + path
+	if e == nil || e.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, e)
+	}
+}
+
+func TestCli_ConfigFileFlagResolution(t *testing.T) {
+	path := writeConfig(t, "config.json", `{"name": "config"}`)
+	c := NewCli("test", "description", "0").ConfigFile("config")
+	c.PersistentFlags().ShortFlag("config", "c")
+	var name, message string
+	c.StringFlag("name", "The name", &name)
+	c.Action(func() error { return nil })
+	var output string
+	render := c.NewSubCommand("render", "render a template")
+	render.StringFlag("config", "The template config", &output).ShortFlag("config", "c")
+	render.StringFlag("message", "The message", &message)
+	render.Action(func() error { return nil })
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--config", path}, "config"},
+		{[]string{"-c", path, "render"}, "config"},
+		{[]string{"render", "--config", path}, ""},
+		{[]string{"render", "-c", path}, ""},
+		{[]string{"render", "--message", "--config", path}, ""},
+		{[]string{"--name", "--config"}, "--config"},
+	}
+	for _, test := range tests {
+		c.ResetFlags()
+		if e := c.Run(test.args...); e != nil {
+			t.Fatalf("%v: expected no error, got %v", test.args, e)
+		}
+		if name != test.expected {
+			t.Errorf("%v: expected name %q, got %q", test.args, test.expected, name)
+		}
+	}
+}
+
+func TestCli_ConfigFileSearchPaths(t *testing.T) {
+	path := writeConfig(t, "config.toml", `name = "found"`)
+	c := NewCli("test", "description", "0").ConfigFile("config", filepath.Join(t.TempDir(), "missing.json"), path)
+	var name string
+	c.StringFlag("name", "The name", &name)
+	c.Action(func() error { return nil })
+
+	if e := c.Run("--other"); e == nil {
+		t.Errorf("expected error for unknown flag")
+	}
+	if name != "found" {
+		t.Errorf("expected name from search path, got %q", name)
+	}
+
+	// An explicit config file must exist
+	if e := c.Run("--config=" + filepath.Join(t.TempDir(), "missing.json")); e == nil {
+		t.Errorf("expected error for missing config file")
+	}
+}
+
+func TestCli_ConfigFileInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.json", `{"port": `},
+		{"config.json", `{"port": "abc"}`},
+		{"config.toml", `[broken`},
+		{"config.yaml", `port`},
+		{"config.txt", `port = 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.name, tt.content)
+			c := NewCli("test", "description", "0").ConfigFile("config")
+			var port int
+			c.IntFlag("port", "The port", &port)
+			c.Action(func() error { return nil })
+			if e := c.Run("--config", path); e == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestDefaultConfigPaths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/config")
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		t.Skip("no user config directory")
+	}
+	paths := DefaultConfigPaths("myapp")
+	if len(paths) != len(configExtensions) {
+		t.Fatalf("expected %d paths, got %v", len(configExtensions), paths)
+	}
+	if paths[0] != filepath.Join(dir, "myapp", "config.json") {
+		t.Errorf("unexpected path %q", paths[0])
+	}
+}
@@ -0,0 +1,81 @@
+package clir
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// countValue is a flag.Value that counts the number of times the flag is
+// given, EG: `-v -v -v` or `-vvv` counts 3
+type countValue int
+
+func newCountValue(val int, p *int) *countValue {
+	*p = val
+	return (*countValue)(p)
+}
+
+// Set - Increments the count when the flag is given without a value. A
+// number, including 0 and 1, sets the count and false resets it.
+func (c *countValue) Set(value string) error {
+	count, err := parseBasic[int](value)
+	switch err {
+	case nil:
+		*c = countValue(count)
+		return nil
+	case errRange:
+		return err
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return errParse
+	}
+	if enabled {
+		*c++
+	} else {
+		*c = 0
+	}
+	return nil
+}
+
+func (c *countValue) String() string { return strconv.Itoa(int(*c)) }
+
+// IsBoolFlag - Returns true so that the flag may be given without a value
+func (c *countValue) IsBoolFlag() bool { return true }
+
+// CountFlag - Adds a flag that counts the number of times it is given,
+// EG: `-v -v -v` or `-vvv` with the short flag `v` sets the variable to 3
+func (c *Command) CountFlag(name, description string, variable *int) *Command {
+	c.flags.Var(newCountValue(*variable, variable), name, description)
+	c.flagCount++
+	return c
+}
+
+// CountFlag - Adds a flag to the root command that counts the number of
+// times it is given.
+func (c *Cli) CountFlag(name, description string, variable *int) *Cli {
+	c.rootCommand.CountFlag(name, description, variable)
+	return c
+}
+
+// addCountField adds a count flag for a struct field of kind int, which
+// is given the `type:"count"` tag
+func (c *Command) addCountField(name, description, defaultValue string, field reflect.Value) {
+	if field.Kind() != reflect.Int {
+		panic("The count type requires an int field: " + name)
+	}
+	if defaultValue != "" {
+		value, err := strconv.Atoi(defaultValue)
+		if err != nil {
+			panic("Invalid default value for count flag")
+		}
+		field.SetInt(int64(value))
+	}
+	c.CountFlag(name, description, field.Addr().Convert(reflect.TypeOf((*int)(nil))).Interface().(*int))
+}
+
+// verbosity is called before a command's action is run with the number
+// of times the verbose flag added by VerbosityFlag was given
+type verbosity struct {
+	count int
+	apply func(count int)
+}
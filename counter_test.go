@@ -0,0 +1,99 @@
+package clir
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+type Level int
+
+type logFlags struct {
+	Verbose int   `name:"verbose" short:"v" type:"count" description:"Increase verbosity"`
+	Quiet   Level `name:"quiet" short:"q" type:"count" default:"1"`
+	Force   bool  `name:"force" short:"f"`
+}
+
+func TestCommand_CountFlags(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	flags := &logFlags{}
+	c.AddFlags(flags).Action(func() error { return nil })
+	if flags.Quiet != 1 {
+		t.Errorf("expected the default count 1, got %d", flags.Quiet)
+	}
+
+	tests := []struct {
+		args    []string
+		verbose int
+		quiet   Level
+	}{
+		{[]string{"-v"}, 1, 1},
+		{[]string{"-v", "-v", "-v"}, 3, 1},
+		{[]string{"-vvv"}, 3, 1},
+		{[]string{"-vfv", "--verbose", "-qq"}, 3, 3},
+		{[]string{"--verbose=5", "-v"}, 6, 1},
+		{[]string{"-vv", "--verbose=false"}, 0, 1},
+		{[]string{"-vv", "--verbose=0"}, 0, 1},
+		{[]string{"-vv", "--verbose=1"}, 1, 1},
+		{[]string{"-v", "--verbose=2"}, 2, 1},
+		{[]string{"--verbose=3", "--verbose=1"}, 1, 1},
+		{[]string{"--verbose=true", "-v"}, 2, 1},
+	}
+	for _, test := range tests {
+		c.ResetFlags()
+		if e := c.Run(test.args...); e != nil {
+			t.Fatalf("%v: expected no error, got %v", test.args, e)
+		}
+		if flags.Verbose != test.verbose || flags.Quiet != test.quiet {
+			t.Errorf("%v: expected %d, %d, got %d, %d", test.args, test.verbose, test.quiet, flags.Verbose, flags.Quiet)
+		}
+	}
+
+	os.Setenv("APP_VERBOSE", "2")
+	defer os.Unsetenv("APP_VERBOSE")
+	c.ResetFlags()
+	c.EnvVar("verbose", "APP_VERBOSE")
+	if e := c.Run("-v"); e != nil || flags.Verbose != 3 {
+		t.Errorf("expected a count of 3 from the environment, got %v, %d", e, flags.Verbose)
+	}
+
+	c.ResetFlags()
+	e := c.Run("--verbose=lots")
+	var invalidValue *InvalidValueError
+	if !errors.As(e, &invalidValue) || invalidValue.Error() != `invalid value "lots" for flag --verbose: parse error` {
+		t.Errorf("expected an invalid value error, got %v", e)
+	}
+}
+
+func TestCommand_CountFlag(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	var verbose int
+	c.CountFlag("verbose", "Increase verbosity", &verbose).ShortFlag("verbose", "v").Action(func() error { return nil })
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if e := c.Run("--help"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	expected := "  -v, --verbose\n    \tIncrease verbosity\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected help to contain %q, got:\n%s", expected, output.String())
+	}
+
+	if e := c.Run("-vv"); e != nil || verbose != 2 {
+		t.Errorf("expected a count of 2, got %v, %d", e, verbose)
+	}
+}
+
+func TestCommand_CountFlagUnsupported(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "The count type requires an int field") {
+			t.Errorf("expected panic for count tag on a string field, got %v", r)
+		}
+	}()
+	NewCli("app", "description", "0").AddFlags(&struct {
+		Verbose string `name:"verbose" type:"count"`
+	}{})
+}
@@ -0,0 +1,60 @@
+package clir
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// visibleSubCommands returns the subcommands that are not hidden
+func (c *Command) visibleSubCommands() []*Command {
+	var result []*Command
+	for _, subcommand := range c.subCommands {
+		if !subcommand.isHidden() {
+			result = append(result, subcommand)
+		}
+	}
+	return result
+}
+
+// walkVisible calls fn for this command and all of its descendants that
+// are not hidden
+func (c *Command) walkVisible(fn func(*Command) error) error {
+	if err := fn(c); err != nil {
+		return err
+	}
+	for _, subcommand := range c.visibleSubCommands() {
+		if err := subcommand.walkVisible(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageName returns the name of the documentation page for the command,
+// EG: `app-db-migrate`
+func (c *Command) pageName() string {
+	return strings.Join(strings.Fields(c.commandPath), "-")
+}
+
+// description returns the long description of the command, falling back
+// to the short description
+func (c *Command) description() string {
+	if c.longdescription != "" {
+		return c.longdescription
+	}
+	return c.shortdescription
+}
+
+// writeFile creates the named file and writes to it using the given function
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
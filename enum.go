@@ -0,0 +1,118 @@
+package clir
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// choiceValue restricts the values accepted by a flag to a set of choices
+type choiceValue struct {
+	flag.Value
+	choices []string
+}
+
+// Set - Sets the underlying value if the given value is one of the choices
+func (v *choiceValue) Set(value string) error {
+	if !isChoice(value, v.choices) {
+		return choiceError(v.choices)
+	}
+	return v.Value.Set(value)
+}
+
+// choiceError returns the error for a value that is not one of the choices
+func choiceError(choices []string) error {
+	return fmt.Errorf("must be one of: %s", strings.Join(choices, ", "))
+}
+
+// isChoice returns true if the value is one of the given choices
+func isChoice(value string, choices []string) bool {
+	for _, choice := range choices {
+		if value == choice {
+			return true
+		}
+	}
+	return false
+}
+
+// underlyingValue returns the value of the flag, without any wrapper added
+// by clir, so that its type may be inspected
+func underlyingValue(f *flag.Flag) flag.Value {
+	if choice, ok := f.Value.(*choiceValue); ok {
+		return choice.Value
+	}
+	return f.Value
+}
+
+// defaultValues returns the default value of the flag as text, or each of
+// the values of a slice flag, so that they may be checked against its
+// choices. The zero value of a flag has no values.
+func defaultValues(f *flag.Flag) []string {
+	value := underlyingValue(f)
+	var variable reflect.Value
+	if bound, ok := value.(boundValue); ok {
+		variable = bound.variable()
+	} else if pointer := reflect.ValueOf(value); pointer.Kind() == reflect.Ptr {
+		variable = pointer.Elem()
+	}
+	if variable.Kind() != reflect.Slice {
+		if isZeroValue(f) {
+			return nil
+		}
+		return []string{f.DefValue}
+	}
+	result := make([]string, variable.Len())
+	for index := range result {
+		elem := variable.Index(index).Interface()
+		if typed, ok := value.(*typedValue); ok {
+			result[index] = typed.kind.format(elem)
+		} else {
+			result[index] = fmt.Sprint(elem)
+		}
+	}
+	return result
+}
+
+// EnumFlag - Adds a string flag that only accepts one of the given choices,
+// EG: `cmd.EnumFlag("output", "The output format", []string{"json", "yaml"}, &output)`
+func (c *Command) EnumFlag(name, description string, choices []string, variable *string) *Command {
+	c.StringFlag(name, description, variable)
+	return c.Choices(name, choices...)
+}
+
+// Choices - Restricts the values accepted by the named flag to the given
+// choices. Values given on the command line, through environment variables
+// or by the config file are rejected if they are not one of the choices.
+// The choices are shown in the help text and offered by shell completion.
+func (c *Command) Choices(name string, choices ...string) *Command {
+	f := c.flags.Lookup(name)
+	if f == nil {
+		panic("Choices() requires flag '" + name + "' to be defined first")
+	}
+	if len(choices) == 0 {
+		panic("Choices() requires at least one choice for flag '" + name + "'")
+	}
+	for _, value := range defaultValues(f) {
+		if !isChoice(value, choices) {
+			panic("Default value '" + value + "' of flag '" + name + "' is not one of its choices")
+		}
+	}
+	f.Value = &choiceValue{Value: underlyingValue(f), choices: choices}
+	c.flagDetail(name).choices = choices
+	return c
+}
+
+// EnumFlag - Adds a string flag to the root command that only accepts one of
+// the given choices.
+func (c *Cli) EnumFlag(name, description string, choices []string, variable *string) *Cli {
+	c.rootCommand.EnumFlag(name, description, choices, variable)
+	return c
+}
+
+// Choices - Restricts the values accepted by the named root command flag to
+// the given choices.
+func (c *Cli) Choices(name string, choices ...string) *Cli {
+	c.rootCommand.Choices(name, choices...)
+	return c
+}
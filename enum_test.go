@@ -0,0 +1,156 @@
+package clir
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type enumFlags struct {
+	Output string   `name:"output" description:"The output format" enum:"json,yaml,table" default:"table"`
+	Levels []string `name:"level" description:"The levels to show" enum:"info,warn,error"`
+	Kind   string   `pos:"1" name:"kind" description:"The kind of resource" enum:"pod,node"`
+}
+
+func newEnumCli() (*Cli, *enumFlags, *string) {
+	c := NewCli("app", "description", "0")
+	flags := &enumFlags{}
+	get := c.NewSubCommand("get", "Get a resource")
+	get.AddFlags(flags)
+	get.Action(func() error { return nil })
+	color := ""
+	c.PersistentFlags().EnumFlag("color", "When to use colour", []string{"auto", "always", "never"}, &color)
+	return c, flags, &color
+}
+
+func TestCommand_EnumFlag(t *testing.T) {
+	c, flags, color := newEnumCli()
+
+	if e := c.Run("get", "--output", "json", "--level", "warn", "--level", "error", "--color", "never", "pod"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Output != "json" || !reflect.DeepEqual(flags.Levels, []string{"warn", "error"}) || flags.Kind != "pod" || *color != "never" {
+		t.Errorf("unexpected values: %+v, color %q", flags, *color)
+	}
+
+	tests := []struct {
+		args    []string
+		flag    string
+		arg     string
+		value   string
+		message string
+	}{
+		{[]string{"get", "--output", "xml"}, "output", "", "xml", `invalid value "xml" for flag --output: must be one of: json, yaml, table`},
+		{[]string{"get", "--level", "debug"}, "level", "", "debug", `invalid value "debug" for flag --level: must be one of: info, warn, error`},
+		{[]string{"get", "--color", "sometimes"}, "color", "", "sometimes", `invalid value "sometimes" for flag --color: must be one of: auto, always, never`},
+		{[]string{"get", "service"}, "", "kind", "service", `invalid value "service" for argument <kind>: must be one of: pod, node`},
+	}
+	for _, test := range tests {
+		e := c.Run(test.args...)
+		var invalidValue *InvalidValueError
+		if !errors.As(e, &invalidValue) {
+			t.Errorf("%v: expected an invalid value error, got %v", test.args, e)
+			continue
+		}
+		if invalidValue.Flag != test.flag || invalidValue.Arg != test.arg || invalidValue.Value != test.value || invalidValue.Error() != test.message {
+			t.Errorf("%v: unexpected error %#v: %s", test.args, invalidValue, invalidValue)
+		}
+	}
+
+	os.Setenv("APP_OUTPUT", "csv")
+	defer os.Unsetenv("APP_OUTPUT")
+	c.rootCommand.subCommandsMap["get"].EnvVar("output", "APP_OUTPUT")
+	if e := c.Run("get"); e == nil || !strings.Contains(e.Error(), "must be one of") {
+		t.Errorf("expected values from the environment to be checked, got %v", e)
+	}
+}
+
+func TestCommand_ChoicesHelp(t *testing.T) {
+	c, _, _ := newEnumCli()
+	help := c.rootCommand.subCommandsMap["get"].Help()
+	for _, f := range help.Flags {
+		if f.Name == "output" {
+			if f.Type != "string" || f.Default != `"table"` || !reflect.DeepEqual(f.Choices, []string{"json", "yaml", "table"}) {
+				t.Errorf("unexpected help for output flag: %+v", f)
+			}
+		}
+	}
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if e := c.Run("get", "--help"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	expected := "  --output string\n    \tThe output format (one of: json, yaml, table) (default \"table\")\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected help to contain %q, got:\n%s", expected, output.String())
+	}
+}
+
+func TestCommand_ChoicesInvalid(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic for a default that is not a choice")
+		}
+	}()
+	format := "xml"
+	NewCli("app", "description", "0").EnumFlag("format", "The format", []string{"json", "yaml"}, &format)
+}
+
+func TestCommand_ChoicesSliceDefault(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	flags := &struct {
+		Formats []string `name:"formats" enum:"json,yaml" default:"json;yaml" sep:";"`
+	}{}
+	c.AddFlags(flags).Action(func() error { return nil })
+
+	if e := c.Run("--formats", "yaml"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !reflect.DeepEqual(flags.Formats, []string{"yaml"}) {
+		t.Errorf("unexpected formats: %v", flags.Formats)
+	}
+
+	defer func() {
+		expected := "Default value 'xml' of flag 'formats' is not one of its choices"
+		if r := recover(); r != expected {
+			t.Errorf("expected panic %q, got %v", expected, r)
+		}
+	}()
+	NewCli("app", "description", "0").AddFlags(&struct {
+		Formats []string `name:"formats" enum:"json,yaml" default:"json,xml" sep:","`
+	}{})
+}
+
+func TestCli_ChoicesCompletion(t *testing.T) {
+	c, _, _ := newEnumCli()
+	if result := c.complete([]string{"get", "--output", "y"}); !reflect.DeepEqual(result, []string{"yaml"}) {
+		t.Errorf("expected choices to be completed, got %v", result)
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	var buffer bytes.Buffer
+	if err := c.WriteCompletion(&buffer, "bash"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	script := filepath.Join(t.TempDir(), "app.bash")
+	if err := os.WriteFile(script, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	command := `source "$1"; shift; COMP_WORDS=("$@"); COMP_CWORD=$(($# - 1)); _app; echo "${COMPREPLY[*]}"`
+	output, err := exec.Command(bash, "-c", command, "bash", script, "app", "get", "--color", "a").CombinedOutput()
+	if err != nil {
+		t.Fatalf("completion failed: %v: %s", err, output)
+	}
+	if strings.TrimSpace(string(output)) != "auto always" {
+		t.Errorf("expected choices to be completed by bash, got %q", output)
+	}
+}
@@ -0,0 +1,54 @@
+package clir
+
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+// envVar returns the name of the environment variable bound to the given
+// flag of this command. An explicitly bound variable takes precedence,
+// otherwise, if the app has an environment prefix, a name is derived from
+// the command path, EG: `MYAPP_DEPLOY_REGION`.
+func (c *Command) envVar(name string, detail *flagDetail) string {
+	if detail != nil && detail.env != "" {
+		return detail.env
+	}
+	if name == "help" || c.app == nil || c.app.envPrefix == "" {
+		return ""
+	}
+	parts := []string{c.app.envPrefix}
+	// The root command is represented by the prefix
+	parts = append(parts, strings.Fields(c.commandPath)[1:]...)
+	parts = append(parts, name)
+	return strings.ToUpper(envReplacer.Replace(strings.Join(parts, "_")))
+}
+
+var envReplacer = strings.NewReplacer("-", "_", ".", "_", " ", "_")
+
+// applyEnv sets the flags defined by this command from their bound
+// environment variables. Flags given on the command line are parsed
+// afterwards so take precedence.
+func (c *Command) applyEnv() error {
+	var err error
+	c.visitOwnFlags(func(f *flag.Flag, detail *flagDetail) {
+		envVar := c.envVar(f.Name, detail)
+		if err != nil || envVar == "" {
+			return
+		}
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			return
+		}
+		if setErr := c.setFlag(f.Name, value, sourceEnv); setErr != nil {
+			err = &InvalidValueError{
+				Command: c.commandPath,
+				Flag:    f.Name,
+				Value:   value,
+				Source:  "environment variable " + envVar,
+				Err:     setErr,
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,114 @@
+package clir
+
+import (
+	"testing"
+)
+
+type EnvFlags struct {
+	Token  string `description:"The API token" env:"APP_TOKEN"`
+	Region string `description:"The region" default:"us"`
+	Port   int    `description:"The port" default:"80"`
+}
+
+func TestCli_EnvTag(t *testing.T) {
+	t.Setenv("APP_TOKEN", "secret")
+	c := NewCli("test", "description", "0")
+	flags := &EnvFlags{}
+	c.AddFlags(flags)
+	c.Action(func() error { return nil })
+
+	if e := c.Run("--region", "eu"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Token != "secret" {
+		t.Errorf("expected token from environment, got %q", flags.Token)
+	}
+	if flags.Region != "eu" {
+		t.Errorf("expected region from command line, got %q", flags.Region)
+	}
+
+	// Command line takes precedence
+	if e := c.Run("--token", "cli"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Token != "cli" {
+		t.Errorf("expected token from command line, got %q", flags.Token)
+	}
+}
+
+func TestCli_EnvPrefix(t *testing.T) {
+	t.Setenv("MYAPP_DEPLOY_REGION", "eu")
+	t.Setenv("MYAPP_DEPLOY_DRY_RUN", "true")
+	t.Setenv("MYAPP_VERBOSE", "true")
+	t.Setenv("MYAPP_DEPLOY_PORT", "8080")
+	t.Setenv("APP_TOKEN", "secret")
+
+	c := NewCli("test", "description", "0").SetEnvPrefix("MYAPP")
+	var verbose bool
+	c.PersistentFlags().BoolFlag("verbose", "Verbose output", &verbose)
+	deploy := c.NewSubCommand("deploy", "deploy the app")
+	flags := &EnvFlags{}
+	deploy.AddFlags(flags)
+	var dryRun bool
+	deploy.BoolFlag("dry-run", "Don't change anything", &dryRun)
+	deploy.Action(func() error { return nil })
+
+	if e := c.Run("deploy", "--port", "9000"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Region != "eu" || !dryRun || !verbose {
+		t.Errorf("expected values from environment, got %+v dryRun=%v verbose=%v", flags, dryRun, verbose)
+	}
+	if flags.Token != "secret" {
+		t.Errorf("expected explicitly bound variable to be used, got %q", flags.Token)
+	}
+	if flags.Port != 9000 {
+		t.Errorf("expected port from command line, got %v", flags.Port)
+	}
+}
+
+func TestCli_EnvVarImperative(t *testing.T) {
+	t.Setenv("APP_NAME", "Janet")
+	c := NewCli("test", "description", "0")
+	var name string
+	c.StringFlag("name", "The name", &name).EnvVar("name", "APP_NAME")
+	c.Action(func() error { return nil })
+	if e := c.rootCommand.run(nil); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if name != "Janet" {
+		t.Errorf("expected name from environment, got %q", name)
+	}
+}
+
+func TestCli_EnvInvalidValue(t *testing.T) {
+	t.Setenv("APP_PORT", "abc")
+	c := NewCli("test", "description", "0")
+	var port int
+	c.IntFlag("port", "The port", &port).EnvVar("port", "APP_PORT")
+	c.Action(func() error { return nil })
+	if e := c.rootCommand.run(nil); e == nil {
+		t.Errorf("expected error for invalid environment value")
+	}
+}
+
+func TestCommand_envVar(t *testing.T) {
+	c := NewCli("myapp", "description", "0").SetEnvPrefix("MYAPP")
+	sub := c.NewSubCommand("db", "database").NewSubCommand("migrate", "migrate")
+	tests := []struct {
+		command *Command
+		flag    string
+		detail  *flagDetail
+		want    string
+	}{
+		{c.rootCommand, "region", nil, "MYAPP_REGION"},
+		{sub, "dry-run", nil, "MYAPP_DB_MIGRATE_DRY_RUN"},
+		{sub, "dry-run", &flagDetail{env: "DRY"}, "DRY"},
+		{sub, "help", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.command.envVar(tt.flag, tt.detail); got != tt.want {
+			t.Errorf("envVar(%q) = %q, want %q", tt.flag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package clir
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UsageError is returned when the application is run with an invalid
+// command line and no error function has been set. Err holds the
+// underlying error, which is usually one of the typed errors below.
+type UsageError struct {
+	// Command is the path of the command that was run, EG: `app deploy`
+	Command string
+	Err     error
+}
+
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("Error: %s\nSee '%s --help' for usage", e.Err, e.Command)
+}
+
+// Unwrap - Returns the underlying error
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownFlagError is returned when a flag is given that the command does
+// not define
+type UnknownFlagError struct {
+	Command string
+	// Flag is the name of the flag, without leading dashes
+	Flag string
+	// Suggestions are the names of defined flags similar to Flag
+	Suggestions []string
+}
+
+func (e *UnknownFlagError) Error() string {
+	var suggestions []string
+	for _, suggestion := range e.Suggestions {
+		suggestions = append(suggestions, "--"+suggestion)
+	}
+	return "flag provided but not defined: --" + e.Flag + didYouMean(suggestions)
+}
+
+// UnknownCommandError is returned when a command that has subcommands is
+// given an argument that is not one of them
+type UnknownCommandError struct {
+	Command string
+	// Name is the subcommand that was given
+	Name string
+	// Suggestions are the names and aliases of visible subcommands similar
+	// to Name
+	Suggestions []string
+}
+
+func (e *UnknownCommandError) Error() string {
+	return fmt.Sprintf("unknown command '%s' for '%s'", e.Name, e.Command) + didYouMean(e.Suggestions)
+}
+
+// InvalidValueError is returned when the value given for a flag or
+// positional argument cannot be parsed
+type InvalidValueError struct {
+	Command string
+	// Flag is the name of the flag. It is blank for positional arguments.
+	Flag string
+	// Arg is the name of the positional argument, if the value was given
+	// for one
+	Arg string
+	// Value is the raw value that was given
+	Value string
+	// Source describes where the value came from when it was not given on
+	// the command line, EG: `environment variable APP_REGION`
+	Source string
+	Err    error
+}
+
+func (e *InvalidValueError) Error() string {
+	switch {
+	case e.Source != "":
+		return fmt.Sprintf("invalid value %q for %s: %v", e.Value, e.Source, e.Err)
+	case e.Arg != "":
+		return fmt.Sprintf("invalid value %q for argument <%s>: %v", e.Value, e.Arg, e.Err)
+	}
+	return fmt.Sprintf("invalid value %q for flag --%s: %v", e.Value, e.Flag, e.Err)
+}
+
+// Unwrap - Returns the underlying error
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// MissingRequiredError is returned when required flags or positional
+// arguments have not been given
+type MissingRequiredError struct {
+	Command string
+	// Flags are the names of the missing flags
+	Flags []string
+	// Args are the names of the missing positional arguments
+	Args []string
+}
+
+func (e *MissingRequiredError) Error() string {
+	var missing []string
+	for _, name := range e.Flags {
+		missing = append(missing, "--"+name)
+	}
+	for _, name := range e.Args {
+		missing = append(missing, "<"+name+">")
+	}
+	return "missing required flags or arguments: " + strings.Join(missing, ", ")
+}
+
+// ValidationError is returned when a value is parsed successfully but is
+// not valid for the command
+type ValidationError struct {
+	Command string
+	// Flag is the name of the flag that failed validation
+	Flag string
+	// Arg is the name of the positional argument that failed validation
+	Arg string
+	// Value is the raw value that was given
+	Value string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	switch {
+	case e.Flag != "":
+		return fmt.Sprintf("validation failed for flag --%s: %v", e.Flag, e.Err)
+	case e.Arg != "":
+		return fmt.Sprintf("validation failed for argument <%s>: %v", e.Arg, e.Err)
+	}
+	return fmt.Sprintf("validation failed: %v", e.Err)
+}
+
+// Unwrap - Returns the underlying error
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is returned when more than one value fails validation
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := []string{"validation failed:"}
+	for _, failure := range e {
+		lines = append(lines, "  "+strings.TrimPrefix(failure.Error(), "validation failed for "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// As - Finds the first of the validation errors that matches target, so
+// that errors.As can inspect them on Go versions before 1.20
+func (e ValidationErrors) As(target interface{}) bool {
+	for _, failure := range e {
+		if errors.As(failure, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap - Returns the individual validation errors. The errors package
+// only uses it on Go 1.20 and later.
+func (e ValidationErrors) Unwrap() []error {
+	result := make([]error, len(e))
+	for index, failure := range e {
+		result[index] = failure
+	}
+	return result
+}
+
+// FlagGroupError is returned when the flags that were set do not meet the
+// constraint of a flag group
+type FlagGroupError struct {
+	Command string
+	Rule    FlagGroupRule
+	// Flag is the flag that requires the others, for RuleRequires
+	Flag string
+	// Flags are the flags in the group
+	Flags []string
+	// Set are the flags in the group that were set
+	Set []string
+}
+
+func (e *FlagGroupError) Error() string {
+	switch {
+	case e.Rule == RuleRequires:
+		var missing []string
+		for _, name := range e.Flags {
+			if !isChoice(name, e.Set) {
+				missing = append(missing, name)
+			}
+		}
+		return "--" + e.Flag + " requires " + flagNames(missing)
+	case len(e.Set) == 0:
+		return "exactly one of " + flagNames(e.Flags) + " is required"
+	}
+	return "only one of " + flagNames(e.Flags) + " may be given, got " + flagNames(e.Set)
+}
+
+// ExitCoder is implemented by errors that determine the code the
+// application exits with when returned from an action
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// ExitCode - Returns the code the application should exit with for the
+// given error: 0 for nil, the error's own code if it is an ExitCoder, 2
+// for an invalid command line and 1 otherwise
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	if isUsageError(err) {
+		return 2
+	}
+	return 1
+}
+
+// isUsageError returns true if the error is caused by an invalid
+// command line
+func isUsageError(err error) bool {
+	var (
+		usage          *UsageError
+		unknownFlag    *UnknownFlagError
+		unknownCommand *UnknownCommandError
+		invalidValue   *InvalidValueError
+		missing        *MissingRequiredError
+		validation     *ValidationError
+		failures       ValidationErrors
+		group          *FlagGroupError
+	)
+	return errors.As(err, &usage) || errors.As(err, &unknownFlag) ||
+		errors.As(err, &unknownCommand) || errors.As(err, &invalidValue) ||
+		errors.As(err, &missing) || errors.As(err, &validation) ||
+		errors.As(err, &failures) || errors.As(err, &group)
+}
+
+// parseError converts an error returned by the flag package into the
+// equivalent typed error. Other errors name the flag as it was given in
+// args, before short flags were expanded.
+func (c *Command) parseError(err error, args []string) error {
+	message := err.Error()
+	if name := strings.TrimPrefix(message, "flag needs an argument: -"); name != message {
+		return errors.New("flag needs an argument: " + c.givenFlagName(name, args))
+	}
+	if name := strings.TrimPrefix(message, "flag provided but not defined: -"); name != message {
+		return &UnknownFlagError{Command: c.commandPath, Flag: name, Suggestions: c.flagSuggestions(name)}
+	}
+	for _, prefix := range []string{"invalid value ", "invalid boolean value "} {
+		rest := strings.TrimPrefix(message, prefix)
+		if rest == message {
+			continue
+		}
+		quoted, quoteErr := strconv.QuotedPrefix(rest)
+		if quoteErr != nil {
+			break
+		}
+		value, _ := strconv.Unquote(quoted)
+		rest = strings.TrimPrefix(rest[len(quoted):], " for ")
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, "flag "), "-")
+		separator := strings.Index(rest, ": ")
+		if separator < 0 {
+			break
+		}
+		return &InvalidValueError{
+			Command: c.commandPath,
+			Flag:    rest[:separator],
+			Value:   value,
+			Err:     errors.New(rest[separator+2:]),
+		}
+	}
+	return err
+}
+
+// givenFlagName returns the named flag as it was given in args, EG: `-o`
+// for the short flag of `output`. The long form is returned if it is not
+// found.
+func (c *Command) givenFlagName(name string, args []string) string {
+	for index := len(args) - 1; index >= 0; index-- {
+		arg := args[index]
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+		given := strings.SplitN(arg, "=", 2)[0]
+		if strings.TrimLeft(given, "-") == name {
+			return given
+		}
+		expanded, _ := c.expandShortFlagCluster(arg[1:])
+		for _, long := range expanded {
+			if strings.SplitN(long, "=", 2)[0] != "--"+name {
+				continue
+			}
+			for short, longName := range c.shortFlags {
+				if longName == name {
+					return "-" + short
+				}
+			}
+		}
+	}
+	return "--" + name
+}
+
+// unknownCommand returns the error for an unknown subcommand with the
+// given name
+func (c *Command) unknownCommand(name string) error {
+	var candidates []string
+	for _, subcommand := range c.visibleSubCommands() {
+		candidates = append(candidates, subcommand.name)
+		candidates = append(candidates, subcommand.aliases...)
+	}
+	return &UnknownCommandError{Command: c.commandPath, Name: name, Suggestions: suggestions(name, candidates)}
+}
+
+// flagSuggestions returns the names of the command's flags that are
+// similar to the given name
+func (c *Command) flagSuggestions(name string) []string {
+	var candidates []string
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if !c.isFlagHidden(f.Name) {
+			candidates = append(candidates, f.Name)
+		}
+	})
+	return suggestions(name, candidates)
+}
@@ -0,0 +1,127 @@
+package clir
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+type exitError struct{ code int }
+
+func (e *exitError) Error() string { return "failed" }
+func (e *exitError) ExitCode() int { return e.code }
+
+func newErrorsCli() *Cli {
+	c := NewCli("app", "An example app", "v1.0.0")
+	deploy := c.NewSubCommand("deploy", "Deploy the app")
+	var count int
+	var region string
+	deploy.IntFlag("count", "The number of instances", &count)
+	deploy.StringFlag("region", "The region", &region)
+	deploy.ShortFlag("region", "r")
+	deploy.Required("region")
+	deploy.Action(func() error {
+		if count < 0 {
+			return &exitError{code: 3}
+		}
+		return nil
+	})
+	return c
+}
+
+func TestCli_TypedErrors(t *testing.T) {
+	c := newErrorsCli()
+
+	err := c.Run("deploy", "--unknown")
+	var usage *UsageError
+	var unknownFlag *UnknownFlagError
+	if !errors.As(err, &usage) || usage.Command != "app deploy" {
+		t.Errorf("expected a usage error, got %#v", err)
+	}
+	if !errors.As(err, &unknownFlag) || unknownFlag.Flag != "unknown" || unknownFlag.Command != "app deploy" {
+		t.Errorf("expected an unknown flag error, got %#v", err)
+	}
+	expected := "Error: flag provided but not defined: --unknown\nSee 'app deploy --help' for usage"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	for _, given := range []string{"-r", "--region", "-region"} {
+		err = c.Run("deploy", given)
+		expected = "Error: flag needs an argument: " + given + "\nSee 'app deploy --help' for usage"
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected %q, got %v", expected, err)
+		}
+	}
+
+	var invalidValue *InvalidValueError
+	err = c.Run("deploy", "--region", "eu", "--count", "many")
+	if !errors.As(err, &invalidValue) || invalidValue.Flag != "count" || invalidValue.Value != "many" {
+		t.Errorf("expected an invalid value error, got %#v", err)
+	}
+
+	os.Setenv("APP_COUNT", "lots")
+	c.rootCommand.subCommandsMap["deploy"].EnvVar("count", "APP_COUNT")
+	err = c.Run("deploy", "--region", "eu")
+	os.Unsetenv("APP_COUNT")
+	if !errors.As(err, &invalidValue) || invalidValue.Source != "environment variable APP_COUNT" || invalidValue.Value != "lots" {
+		t.Errorf("expected an invalid value error from the environment, got %#v", err)
+	}
+
+	var missing *MissingRequiredError
+	err = c.Run("deploy")
+	if !errors.As(err, &missing) || len(missing.Flags) != 1 || missing.Flags[0] != "region" {
+		t.Errorf("expected a missing required error, got %#v", err)
+	}
+
+	var unknownCommand *UnknownCommandError
+	err = c.Run("depoly")
+	if !errors.As(err, &unknownCommand) || unknownCommand.Name != "depoly" || unknownCommand.Command != "app" {
+		t.Errorf("expected an unknown command error, got %#v", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	c := newErrorsCli()
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"deploy", "--region", "eu"}, 0},
+		{[]string{"deploy", "--region", "eu", "--count", "-1"}, 3},
+		{[]string{"deploy", "--count", "1"}, 2},
+		{[]string{"deploy", "--region", "eu", "--count", "x"}, 2},
+		{[]string{"nope"}, 2},
+	}
+	for _, test := range tests {
+		if code := ExitCode(c.Run(test.args...)); code != test.expected {
+			t.Errorf("%v: expected exit code %d, got %d", test.args, test.expected, code)
+		}
+	}
+	if code := ExitCode(errors.New("failed")); code != 1 {
+		t.Errorf("expected exit code 1 for other errors, got %d", code)
+	}
+}
+
+func TestCli_RunAndExit(t *testing.T) {
+	var exitCode int
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
+
+	var errOutput bytes.Buffer
+	c := newErrorsCli().SetErrOutput(&errOutput)
+	c.RunAndExit("deploy", "--bogus")
+	if exitCode != 2 {
+		t.Errorf("expected exit code 2, got %d", exitCode)
+	}
+	if !strings.Contains(errOutput.String(), "flag provided but not defined: --bogus") {
+		t.Errorf("expected error to be printed, got %q", errOutput.String())
+	}
+
+	c.RunAndExit("deploy", "--region", "eu")
+	if exitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", exitCode)
+	}
+}
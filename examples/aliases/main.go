@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("aliases", "An example of command aliases", "v0.0.1")
+
+	// Allow commands to be run by an unambiguous prefix, EG: `aliases dep`
+	cli.EnablePrefixMatching()
+
+	// The remove command may also be run as `rm` or `del`
+	cli.NewSubCommand("remove", "Remove an item").
+		Aliases("rm", "del").
+		Action(func() error {
+			fmt.Println("Removing")
+			return nil
+		})
+
+	cli.NewSubCommand("deploy", "Deploy the app").
+		Action(func() error {
+			fmt.Println("Deploying")
+			return nil
+		})
+
+	// Run!
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+func main() {
+
+	// Create new cli with a hidden `completion` command
+	cli := clir.NewCli("completion", "An example of shell completion", "v0.0.1").
+		EnableCompletion()
+
+	var verbose bool
+	cli.PersistentFlags().BoolFlag("verbose", "Show more output", &verbose).ShortFlag("verbose", "v")
+
+	db := cli.NewSubCommand("db", "Database commands")
+	migrate := db.NewSubCommand("migrate", "Migrate the database")
+	var dryRun bool
+	migrate.BoolFlag("dry-run", "Don't apply changes", &dryRun)
+	// Values for --target are looked up when the user presses TAB
+	var target string
+	migrate.StringFlag("target", "The migration to migrate to", &target).
+		CompleteFlag("target", func(prefix string, args []string) []string {
+			return []string{"0001_init", "0002_users", "0003_orders"}
+		})
+	migrate.Action(func() error {
+		fmt.Println("Migrating to", target, "Dry run:", dryRun)
+		return nil
+	})
+
+	// Try: source <(completion completion bash), then: completion db migrate --target <TAB>
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type DeployFlags struct {
+	Region   string `name:"region" description:"The region to deploy to" default:"us-east-1"`
+	Replicas int    `name:"replicas" description:"The number of replicas" default:"1"`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("config-file", "An example of loading flags from a config file", "v0.0.1")
+
+	// Load the config file given by the --config flag, or from the
+	// default location, EG: ~/.config/config-file/config.yaml
+	cli.ConfigFile("config", clir.DefaultConfigPaths("config-file")...)
+
+	cli.NewSubCommandFunction("deploy", "Deploy the app", func(flags *DeployFlags) error {
+		fmt.Printf("Deploying %d replicas to %s\n", flags.Replicas, flags.Region)
+		return nil
+	})
+
+	// Try: config-file --config config.yaml deploy
+	//
+	// config.yaml:
+	//   deploy:
+	//     region: eu-west-1
+	//     replicas: 3
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
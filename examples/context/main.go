@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/leaanthony/clir"
+)
+
+type DeployFlags struct {
+	Steps int `name:"steps" description:"The number of steps to run" default:"10"`
+}
+
+func main() {
+
+	// Create new cli. The context is cancelled on Ctrl-C
+	cli := clir.NewCli("context", "An example of context aware actions", "v0.0.1").
+		HandleSignals()
+
+	cli.NewSubCommandFunction("deploy", "Deploy the app", func(ctx context.Context, flags *DeployFlags) error {
+		for step := 1; step <= flags.Steps; step++ {
+			select {
+			case <-ctx.Done():
+				fmt.Println("Interrupted. Cleaning up...")
+				return ctx.Err()
+			case <-time.After(time.Second):
+				fmt.Printf("Step %d of %d\n", step, flags.Steps)
+			}
+		}
+		return nil
+	})
+
+	// Deadlines may be given using RunContext
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Try: context deploy, then press Ctrl-C
+	if err := cli.RunContext(ctx); err != nil {
+		fmt.Println(err)
+	}
+
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func customFlagError(cmdPath string, err error) error {
-	return fmt.Errorf(`%s 
+	return fmt.Errorf(`%w 
 flag v0.0.1 - A custom error example
 
 Flags:
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/leaanthony/clir"
+)
+
+const helpTemplate = `{{.Banner}}
+
+USAGE
+  {{.Usage}}
+{{if .Commands}}
+COMMANDS
+{{range .Commands}}  {{pad 10 .Name}}{{.ShortDescription}}
+{{end}}{{end}}{{if .Flags}}
+FLAGS
+{{range .Flags}}  {{pad 16 .Names}}{{.Usage}}{{if .Default}} (default {{.Default}}){{end}}
+{{end}}{{end}}`
+
+func main() {
+
+	// Create new cli with branded help
+	cli := clir.NewCli("custom-help", "An example of custom help", "v0.0.1").
+		SetHelpRenderer(clir.HelpTemplate(helpTemplate))
+
+	name := "World"
+	cli.StringFlag("name", "The name to greet", &name).ShortFlag("name", "n")
+
+	// Internal commands get terse help
+	internal := cli.NewSubCommand("internal", "Internal tools")
+	internal.SetHelpRenderer(clir.HelpRendererFunc(func(w io.Writer, help *clir.Help) error {
+		_, err := fmt.Fprintf(w, "usage: %s\n", help.Usage)
+		return err
+	}))
+	internal.NewSubCommand("reset", "Reset everything")
+
+	cli.Action(func() error {
+		fmt.Printf("Hello %s!\n", name)
+		return nil
+	})
+
+	// Try: custom-help --help, then: custom-help internal --help
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
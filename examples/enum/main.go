@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type listFlags struct {
+	Sort string `name:"sort" description:"The field to sort by" enum:"name,size,date" default:"name"`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("enum", "An example of flags restricted to a set of values", "v0.0.1")
+	cli.EnableCompletion()
+
+	// Imperative flags
+	output := "table"
+	cli.EnumFlag("output", "The output format", []string{"json", "yaml", "table"}, &output)
+
+	// Struct flags use the enum tag
+	flags := &listFlags{}
+	cli.AddFlags(flags)
+
+	cli.Action(func() error {
+		fmt.Printf("Listing sorted by %s as %s\n", flags.Sort, output)
+		return nil
+	})
+
+	// Values that are not one of the choices are rejected, EG:
+	// Error: invalid value "xml" for flag --output: must be one of: json, yaml, table
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
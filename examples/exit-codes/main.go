@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+// deployError is returned when a deployment fails. It implements
+// clir.ExitCoder to choose the code the application exits with.
+type deployError struct {
+	region string
+}
+
+func (e *deployError) Error() string {
+	return "unable to deploy to " + e.region
+}
+
+func (e *deployError) ExitCode() int {
+	return 3
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("exit-codes", "An example of errors and exit codes", "v0.0.1")
+
+	deploy := cli.NewSubCommand("deploy", "Deploy the app")
+	var region string
+	deploy.StringFlag("region", "The region to deploy to", &region).
+		Required("region")
+	deploy.Action(func() error {
+		if region == "moon" {
+			return &deployError{region: region}
+		}
+		fmt.Fprintf(cli.Output(), "Deployed to %s\n", region)
+		return nil
+	})
+
+	// Errors describe what was wrong with the command line
+	err := cli.Run("deploy", "--regoin", "eu")
+	var unknownFlag *clir.UnknownFlagError
+	if errors.As(err, &unknownFlag) {
+		fmt.Printf("Unknown flag '%s' for '%s' (exit code %d)\n", unknownFlag.Flag, unknownFlag.Command, clir.ExitCode(err))
+	}
+
+	// Actions may choose their own exit code
+	err = cli.Run("deploy", "--region", "moon")
+	fmt.Printf("%v (exit code %d)\n", err, clir.ExitCode(err))
+
+	// Prints any error and exits with its code
+	cli.RunAndExit()
+
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type importFlags struct {
+	File  string `name:"file" description:"Read from a file" xor:"source"`
+	URL   string `name:"url" description:"Read from a URL" xor:"source"`
+	Stdin bool   `name:"stdin" description:"Read from stdin" xor:"source"`
+	Key   string `name:"key" description:"The client key" requires:"cert"`
+	Cert  string `name:"cert" description:"The client certificate"`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("flag-groups", "An example of flag groups", "v0.0.1")
+
+	// Struct flags use the xor, exclusive and requires tags
+	importCommand := cli.NewSubCommand("import", "Import data")
+	flags := &importFlags{}
+	importCommand.AddFlags(flags)
+
+	// Imperative flags use ExactlyOneOf, AtMostOneOf and Requires
+	var json, yaml bool
+	importCommand.BoolFlag("json", "Output JSON", &json)
+	importCommand.BoolFlag("yaml", "Output YAML", &yaml)
+	importCommand.AtMostOneOf("json", "yaml")
+
+	importCommand.Action(func() error {
+		if importCommand.IsSet("url") {
+			fmt.Println("Importing from", flags.URL)
+		}
+		return nil
+	})
+
+	// Flags that break a group's rule are rejected, EG:
+	// Error: only one of --file, --url, --stdin may be given, got --file, --url
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
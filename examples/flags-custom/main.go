@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/leaanthony/clir"
+)
+
+// Region implements flag.Value
+type Region string
+
+func (r *Region) String() string { return string(*r) }
+
+func (r *Region) Set(value string) error {
+	if strings.Count(value, "-") != 2 {
+		return errors.New("regions are of the form 'eu-west-1'")
+	}
+	*r = Region(value)
+	return nil
+}
+
+// SemVer implements encoding.TextUnmarshaler
+type SemVer struct {
+	Major, Minor, Patch int
+}
+
+func (v *SemVer) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "v%d.%d.%d", &v.Major, &v.Minor, &v.Patch)
+	return err
+}
+
+func (v SemVer) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+type releaseFlags struct {
+	Version SemVer `pos:"1" name:"version" description:"The version to release"`
+	Region  Region `name:"region" description:"The region to release to" default:"eu-west-1"`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("flags-custom", "An example of flags using custom types", "v0.0.1")
+
+	cli.NewSubCommandFunction("release", "Release a version", func(flags *releaseFlags) error {
+		fmt.Printf("Releasing %s to %s\n", flags.Version, flags.Region)
+		return nil
+	})
+
+	// Run!
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type DeployFlags struct {
+	Token  string `name:"token" description:"The API token" env:"APP_TOKEN"`
+	Region string `name:"region" description:"The region to deploy to" default:"us-east-1"`
+}
+
+func main() {
+
+	// Create new cli. All flags are bound to environment variables
+	// prefixed with "MYAPP"
+	cli := clir.NewCli("flags-env", "An example of environment variables", "v0.0.1").
+		SetEnvPrefix("MYAPP")
+
+	// The region flag is bound to MYAPP_DEPLOY_REGION
+	cli.NewSubCommandFunction("deploy", "Deploy the app", func(flags *DeployFlags) error {
+		fmt.Printf("Deploying to %s with token %q\n", flags.Region, flags.Token)
+		return nil
+	})
+
+	// Try: MYAPP_DEPLOY_REGION=eu-west-1 APP_TOKEN=secret flags-env deploy
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
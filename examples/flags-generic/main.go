@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/leaanthony/clir"
+)
+
+// Port is a named type, which uses the flag type of its underlying type
+type Port int
+
+// Point is registered with a parser so it may be used as a flag
+type Point struct {
+	X, Y int
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("%d,%d", p.X, p.Y)
+}
+
+func main() {
+
+	// Register the parser for points
+	clir.RegisterType(func(text string) (Point, error) {
+		var result Point
+		_, err := fmt.Sscanf(text, "%d,%d", &result.X, &result.Y)
+		return result, err
+	})
+
+	// Create new cli
+	cli := clir.NewCli("flags-generic", "An example of the generic flag API", "v0.0.1")
+
+	var port Port = 8080
+	timeout := 30 * time.Second
+	var tags []string
+	var origin Point
+	format := "text"
+	clir.Flag(cli, "port", "The port to listen on", &port, clir.WithShort("p"), clir.WithEnv("PORT"))
+	clir.Flag(cli, "timeout", "The request timeout", &timeout)
+	clir.Flag(cli, "tag", "A tag to apply", &tags)
+	clir.Flag(cli, "origin", "The origin of the map", &origin, clir.WithRequired())
+	clir.Flag(cli, "format", "The log format", &format, clir.WithEnum("text", "json"), clir.WithHidden())
+
+	cli.Action(func() error {
+		fmt.Printf("Listening on %d with a timeout of %s, tags %v, origin %s and %s logs\n", port, timeout, tags, origin, format)
+		return nil
+	})
+
+	// Run!
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
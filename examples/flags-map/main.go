@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type deployFlags struct {
+	Labels    map[string]string `name:"label" description:"The labels to apply" default:"env=dev"`
+	Replicas  map[string]int    `name:"replicas" description:"The replicas by region"`
+	BuildArgs map[string]string `name:"build-arg" description:"The build arguments" sep:";"`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("flags-map", "An example of map flags", "v0.0.1")
+
+	// Pairs may be given one at a time or together, EG:
+	// flags-map deploy --label env=prod --label team=core,tier=web --replicas eu=3
+	cli.NewSubCommandFunction("deploy", "Deploy the app", func(flags *deployFlags) error {
+		fmt.Printf("Deploying with labels %v, replicas %v and build args %v\n", flags.Labels, flags.Replicas, flags.BuildArgs)
+		return nil
+	})
+
+	// Imperative map flags use StringMapFlag
+	annotations := map[string]string{}
+	cli.StringMapFlag("annotation", "An annotation to add", &annotations)
+
+	// Run!
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
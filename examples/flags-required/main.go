@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type CopyFlags struct {
+	Source      string `pos:"1" description:"The file to copy" required:"true"`
+	Destination string `pos:"2" description:"Where to copy the file"`
+	Mode        string `name:"mode" description:"The file mode" required:"true"`
+	Force       bool   `name:"force" description:"Overwrite existing files"`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("flags-required", "An example of required flags", "v0.0.1")
+
+	cli.NewSubCommandFunction("copy", "Copy a file", func(flags *CopyFlags) error {
+		fmt.Printf("Copying %s to %s with mode %s\n", flags.Source, flags.Destination, flags.Mode)
+		return nil
+	})
+
+	// Required flags may also be set on imperative flags
+	var token string
+	upload := cli.NewSubCommand("upload", "Upload a file")
+	upload.StringFlag("token", "The API token", &token).
+		Required("token")
+	upload.Action(func() error {
+		fmt.Println("Uploading with token", token)
+		return nil
+	})
+
+	// Try: flags-required copy
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type Flags struct {
+	Verbose bool   `name:"verbose" description:"Show more output" short:"v"`
+	Force   bool   `name:"force" description:"Overwrite existing files" short:"f"`
+	Output  string `name:"output" description:"The output file" short:"o" default:"out.txt"`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("flags-short", "An example of using short flags", "v0.0.1")
+
+	// Add flags from a struct
+	flags := &Flags{}
+	cli.AddFlags(flags)
+
+	// Short flags may also be added to existing flags
+	var name string
+	cli.StringFlag("name", "Your name", &name).
+		ShortFlag("name", "n")
+
+	// Define action
+	cli.Action(func() error {
+		fmt.Printf("Name: %s, Verbose: %v, Force: %v, Output: %s\n", name, flags.Verbose, flags.Force, flags.Output)
+		return nil
+	})
+
+	// Try: flags-short -vf -o result.txt -n Janet
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
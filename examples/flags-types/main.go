@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/leaanthony/clir"
+)
+
+type pingFlags struct {
+	Host     net.IP          `pos:"1" name:"host" description:"The host to ping"`
+	Timeout  time.Duration   `name:"timeout" description:"How long to wait for a reply" default:"5s"`
+	Since    time.Time       `name:"since" description:"Only show results since this date" layout:"2006-01-02"`
+	Network  net.IPNet       `name:"network" description:"The network to scan"`
+	Report   *url.URL        `name:"report" description:"The URL to send the report to"`
+	Filter   *regexp.Regexp  `name:"filter" description:"Only show hosts matching this pattern"`
+	Mode     os.FileMode     `name:"mode" description:"The mode of the report file" default:"0644"`
+	Backoffs []time.Duration `name:"backoff" description:"The delays between retries" default:"1s,5s" sep:","`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("flags-types", "An example of flags using standard library types", "v0.0.1")
+
+	cli.NewSubCommandFunction("ping", "Ping a host", func(flags *pingFlags) error {
+		fmt.Printf("Pinging %s with a timeout of %s, retrying after %v\n", flags.Host, flags.Timeout, flags.Backoffs)
+		return nil
+	})
+
+	// Run!
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
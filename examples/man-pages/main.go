@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+func main() {
+
+	// Create new cli with a hidden `gen-man` command
+	cli := clir.NewCli("man-pages", "An example of generating man pages", "v0.0.1").
+		EnableManPages()
+
+	var verbose bool
+	cli.PersistentFlags().BoolFlag("verbose", "Show more output", &verbose).ShortFlag("verbose", "v")
+
+	db := cli.NewSubCommand("db", "Database commands")
+	db.LongDescription("Commands for managing the database.")
+	migrate := db.NewSubCommand("migrate", "Migrate the database")
+	steps := 1
+	migrate.IntFlag("steps", "The number of migrations to apply", &steps).
+		EnvVar("steps", "MIGRATE_STEPS")
+	migrate.Action(func() error {
+		fmt.Println("Applying", steps, "migrations")
+		return nil
+	})
+
+	// Try: man-pages gen-man ./man, then: man ./man/man-pages-db-migrate.1
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/leaanthony/clir"
+)
+
+// Keep the command reference in sync with the binary:
+//go:generate go run . --gen-docs ./docs
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("markdown-docs", "An example of generating Markdown docs", "v0.0.1")
+
+	var docsDir string
+	cli.StringFlag("gen-docs", "Write the Markdown reference to the given directory", &docsDir)
+
+	db := cli.NewSubCommand("db", "Database commands")
+	migrate := db.NewSubCommand("migrate", "Migrate the database")
+	var dryRun bool
+	migrate.BoolFlag("dry-run", "Don't apply changes", &dryRun)
+	migrate.Action(func() error {
+		fmt.Println("Migrating. Dry run:", dryRun)
+		return nil
+	})
+
+	cli.Action(func() error {
+		if docsDir == "" {
+			cli.PrintHelp()
+			return nil
+		}
+		return cli.WriteMarkdownDocs(docsDir)
+	})
+
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+func main() {
+
+	// Capture everything the application prints
+	var output, errOutput bytes.Buffer
+	cli := clir.NewCli("output", "An example of redirecting output", "v0.0.1").
+		SetOutput(&output).
+		SetErrOutput(&errOutput)
+
+	name := "World"
+	cli.StringFlag("name", "The name to greet", &name)
+
+	// Actions print to the configured output so it can be captured
+	cli.Action(func() error {
+		fmt.Fprintf(cli.Output(), "Hello %s!\n", name)
+		return nil
+	})
+
+	if err := cli.Run("--name", "Debbie"); err != nil {
+		fmt.Println(err)
+	}
+	if err := cli.Run("--help"); err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Printf("Captured %d bytes of output:\n\n%s", output.Len(), output.String())
+
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type GlobalFlags struct {
+	Verbose bool   `name:"verbose" description:"Show more output" short:"v"`
+	Config  string `name:"config" description:"The config file to use" default:"config.json"`
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("persistent-flags", "An example of persistent flags", "v0.0.1")
+
+	// Create nested subcommands
+	db := cli.NewSubCommand("db", "Database commands")
+	migrate := db.NewSubCommand("migrate", "Migrate the database")
+	migrate.Action(func() error {
+		fmt.Println("Migrating database")
+		return nil
+	})
+
+	// Persistent flags may be added after the subcommands are created
+	globals := &GlobalFlags{}
+	cli.PersistentFlags().AddFlags(globals)
+
+	// Persistent flags may be added to any command
+	var region string
+	db.PersistentFlags().StringFlag("region", "The database region", &region)
+
+	// Try: persistent-flags db migrate --help
+	// Try: persistent-flags db migrate -v --region eu
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Verbose: %v, Config: %s, Region: %s\n", globals.Verbose, globals.Config, region)
+
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("suggestions", "An example of suggestions for mistyped names", "v0.0.1")
+
+	deploy := cli.NewSubCommand("deploy", "Deploy the app")
+	var region string
+	deploy.StringFlag("region", "The region to deploy to", &region)
+	deploy.Action(func() error {
+		fmt.Fprintf(cli.Output(), "Deployed to %s\n", region)
+		return nil
+	})
+
+	// Prints:
+	// Error: unknown command 'depoly' for 'suggestions', did you mean 'deploy'?
+	fmt.Println(cli.Run("depoly", "--region", "eu"))
+
+	// Suggestions are available to custom error handling
+	err := cli.Run("deploy", "--regoin", "eu")
+	var unknownFlag *clir.UnknownFlagError
+	if errors.As(err, &unknownFlag) {
+		fmt.Printf("Unknown flag '%s'. Suggestions: %v\n", unknownFlag.Flag, unknownFlag.Suggestions)
+	}
+
+}
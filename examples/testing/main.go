@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leaanthony/clir"
+	"github.com/leaanthony/clir/clirtest"
+)
+
+func newCli() *clir.Cli {
+	cli := clir.NewCli("testing", "An example of testing an application", "v0.0.1")
+
+	greet := cli.NewSubCommand("greet", "Greet someone")
+	name := "World"
+	greet.StringFlag("name", "The name to greet", &name)
+
+	// Actions print to the configured output so it can be captured
+	greet.Action(func() error {
+		fmt.Fprintf(cli.Output(), "Hello %s!\n", name)
+		return nil
+	})
+	return cli
+}
+
+func main() {
+
+	cli := newCli()
+
+	// Usually called from a test, clirtest runs the application in-process
+	result := clirtest.Run(cli, "greet", "--name", "Debbie")
+	fmt.Printf("Command: %s\n", result.Command)
+	fmt.Printf("Flags: %v\n", result.Flags)
+	fmt.Printf("Exit code: %d\n", result.ExitCode)
+	fmt.Printf("Output: %s", result.Stdout)
+
+	// Flags are reset between runs, so the default name is used again
+	result = clirtest.Run(cli, "greet")
+	fmt.Printf("Output: %s", result.Stdout)
+
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leaanthony/clir"
+)
+
+type deployFlags struct {
+	Replicas int    `name:"replicas" description:"The number of replicas" min:"1" max:"10" default:"1"`
+	Name     string `name:"name" description:"The name of the deployment" nonempty:"true" regex:"^[a-z-]+$"`
+	Endpoint string `name:"endpoint" description:"The URL to deploy to" url:"true"`
+	Config   string `name:"config" description:"The config file" file-exists:"true"`
+	User     string `name:"user" description:"The user to deploy as"`
+	Password string `name:"password" description:"The password of the user"`
+}
+
+// Validate is called after the tags have been checked
+func (f *deployFlags) Validate() error {
+	if f.User != "" && f.Password == "" {
+		return errors.New("a password is required with a user")
+	}
+	return nil
+}
+
+func main() {
+
+	// Create new cli
+	cli := clir.NewCli("validation", "An example of validating flags", "v0.0.1")
+
+	cli.NewSubCommandFunction("deploy", "Deploy the app", func(flags *deployFlags) error {
+		fmt.Printf("Deploying %d replicas of %s\n", flags.Replicas, flags.Name)
+		return nil
+	})
+
+	// Every failure is reported together, EG:
+	// Error: validation failed:
+	//   flag --replicas: must be at least 1
+	//   flag --name: must not be empty
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
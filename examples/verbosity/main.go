@@ -0,0 +1,47 @@
+//go:build go1.21
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/leaanthony/clir"
+)
+
+type buildFlags struct {
+	Quiet int `name:"quiet" short:"q" type:"count" description:"Decrease output, may be repeated"`
+}
+
+func main() {
+
+	// Log to stderr at the level set by the verbosity flag
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &level})))
+
+	// Create new cli
+	cli := clir.NewCli("verbosity", "An example of counting flags", "v0.0.1")
+
+	// Each -v lowers the log level, EG:
+	// verbosity build -v logs info messages and verbosity build -vv logs debug messages
+	cli.VerbosityFlag(&level)
+
+	// Count flags in structs use the count type
+	cli.NewSubCommandFunction("build", "Build the app", func(flags *buildFlags) error {
+		slog.Debug("Resolving dependencies")
+		slog.Info("Building app")
+		slog.Warn("No tests found")
+		if flags.Quiet < 2 {
+			fmt.Println("Build complete")
+		}
+		return nil
+	})
+
+	// Run!
+	if err := cli.Run(); err != nil {
+		fmt.Println(err)
+	}
+
+}
@@ -0,0 +1,397 @@
+package clir
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// basicType is the set of types supported by the flag methods for basic
+// values, such as IntsFlag
+type basicType interface {
+	~bool | ~string | ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+var (
+	errParse = errors.New("parse error")
+	errRange = errors.New("value out of range")
+)
+
+// parseBasic parses the text as a value of the given basic type. Errors
+// match those of the flag package.
+func parseBasic[T basicType](text string) (T, error) {
+	var result T
+	value, err := parseBasicValue(text, reflect.TypeOf(result))
+	return value.Interface().(T), err
+}
+
+// parseBasicValue parses the text as a value of the given type, which must
+// be of a basic kind
+func parseBasicValue(text string, typ reflect.Type) (reflect.Value, error) {
+	value := reflect.New(typ).Elem()
+	var err error
+	switch value.Kind() {
+	case reflect.Bool:
+		var parsed bool
+		parsed, err = strconv.ParseBool(text)
+		value.SetBool(parsed)
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var parsed int64
+		parsed, err = strconv.ParseInt(text, 10, typ.Bits())
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var parsed uint64
+		parsed, err = strconv.ParseUint(text, 10, typ.Bits())
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		var parsed float64
+		parsed, err = strconv.ParseFloat(text, typ.Bits())
+		value.SetFloat(parsed)
+	}
+	var numError *strconv.NumError
+	if errors.As(err, &numError) {
+		if numError.Err == strconv.ErrRange {
+			return value, errRange
+		}
+		return value, errParse
+	}
+	return value, err
+}
+
+// basicValue is a flag.Value for a basic type that the flag package does
+// not support, such as int8
+type basicValue[T basicType] struct {
+	value *T
+}
+
+func newBasicValue[T basicType](val T, p *T) *basicValue[T] {
+	*p = val
+	return &basicValue[T]{value: p}
+}
+
+func (f *basicValue[T]) Set(value string) error {
+	parsed, err := parseBasic[T](value)
+	if err != nil {
+		return err
+	}
+	*f.value = parsed
+	return nil
+}
+
+func (f *basicValue[T]) String() string {
+	if f.value == nil {
+		var zero T
+		return fmt.Sprint(zero)
+	}
+	return fmt.Sprint(*f.value)
+}
+
+func (f *basicValue[T]) variable() reflect.Value {
+	return reflect.ValueOf(f.value).Elem()
+}
+
+// sliceValue is a flag.Value that appends each value it is given to a
+// slice of a basic type
+type sliceValue[T basicType] []T
+
+func newSliceValue[T basicType](val []T, p *[]T) *sliceValue[T] {
+	*p = val
+	return (*sliceValue[T])(p)
+}
+
+func (f *sliceValue[T]) Set(value string) error {
+	var parsed T
+	if value != "" || !f.IsBoolFlag() {
+		var err error
+		if parsed, err = parseBasic[T](value); err != nil {
+			return err
+		}
+	}
+	*f = append(*f, parsed)
+	return nil
+}
+
+func (f *sliceValue[T]) String() string { return fmt.Sprint([]T(*f)) }
+
+// IsBoolFlag - Returns true for slices of booleans, so that they may be
+// given without a value
+func (f *sliceValue[T]) IsBoolFlag() bool {
+	var zero T
+	return reflect.ValueOf(zero).Kind() == reflect.Bool
+}
+
+// namedSliceValue is a flag.Value that appends each value it is given to a
+// slice of a named basic type, EG: `[]Port` where `type Port int`
+type namedSliceValue struct {
+	target reflect.Value
+}
+
+// Set - Parses the value and appends it to the slice
+func (v *namedSliceValue) Set(text string) error {
+	elem := v.target.Type().Elem()
+	if text == "" && v.IsBoolFlag() {
+		v.target.Set(reflect.Append(v.target, reflect.Zero(elem)))
+		return nil
+	}
+	value, err := parseBasicValue(text, elem)
+	if err != nil {
+		return err
+	}
+	v.target.Set(reflect.Append(v.target, value))
+	return nil
+}
+
+func (v *namedSliceValue) String() string {
+	if v == nil || !v.target.IsValid() {
+		return "[]"
+	}
+	return fmt.Sprint(v.target.Interface())
+}
+
+// IsBoolFlag - Returns true for slices of booleans, so that they may be
+// given without a value
+func (v *namedSliceValue) IsBoolFlag() bool {
+	return v.target.Type().Elem().Kind() == reflect.Bool
+}
+
+func (v *namedSliceValue) variable() reflect.Value {
+	return v.target
+}
+
+// FlagOption configures a flag added using Flag
+type FlagOption func(c *Command, name string)
+
+// WithShort - Adds a single character alias for the flag
+func WithShort(short string) FlagOption {
+	return func(c *Command, name string) {
+		c.ShortFlag(name, short)
+	}
+}
+
+// WithEnv - Binds the flag to the given environment variable
+func WithEnv(envVar string) FlagOption {
+	return func(c *Command, name string) {
+		c.EnvVar(name, envVar)
+	}
+}
+
+// WithRequired - Marks the flag as required
+func WithRequired() FlagOption {
+	return func(c *Command, name string) {
+		c.Required(name)
+	}
+}
+
+// WithHidden - Hides the flag from the help text, generated documentation,
+// shell completion and suggestions
+func WithHidden() FlagOption {
+	return func(c *Command, name string) {
+		c.HideFlags(name)
+	}
+}
+
+// WithEnum - Restricts the values accepted by the flag to the given choices
+func WithEnum(choices ...string) FlagOption {
+	return func(c *Command, name string) {
+		c.Choices(name, choices...)
+	}
+}
+
+// Flag - Adds a flag of type T to the command or application, EG:
+// `clir.Flag(cmd, "timeout", "How long to wait", &timeout, clir.WithShort("t"))`.
+// T may be any type supported by AddFlags, a slice or map of one or a type
+// registered using RegisterType. The current value of the variable is the
+// flag's default. It panics if the type is not supported.
+func Flag[T any, C interface{ *Command | *Cli }](target C, name, description string, variable *T, options ...FlagOption) C {
+	var command *Command
+	switch target := any(target).(type) {
+	case *Cli:
+		command = target.rootCommand
+	case *Command:
+		command = target
+	}
+	command.addFlag(name, description, reflect.ValueOf(variable).Elem())
+	for _, option := range options {
+		option(command, name)
+	}
+	return target
+}
+
+// RegisterType - Registers the parser for values of type T, so that flags
+// of type T, and slices of them, may be added using Flag or AddFlags.
+// Values are shown in the help text using their String or MarshalText
+// method if they have one. Types must be registered before flags of the
+// type are added.
+func RegisterType[T any](parse func(text string) (T, error)) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	valueTypesLock.Lock()
+	defer valueTypesLock.Unlock()
+	valueTypes[typ] = &valueType{
+		name: "value",
+		parse: func(text string) (interface{}, error) {
+			value, err := parse(text)
+			if err != nil {
+				return nil, err
+			}
+			return value, nil
+		},
+		format: func(value interface{}) string {
+			receiver := reflect.New(typ)
+			receiver.Elem().Set(reflect.ValueOf(value))
+			return formatText(receiver.Interface())
+		},
+	}
+}
+
+// basicTypes are the unnamed basic types, by kind
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.String:  reflect.TypeOf(""),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+}
+
+// isBasicType returns true if the type is of a basic kind, or is a slice
+// of one
+func isBasicType(typ reflect.Type) bool {
+	return basicTypes[basicKind(typ)] != nil
+}
+
+// basicKind returns the kind of the type, or of its elements if it is a
+// slice
+func basicKind(typ reflect.Type) reflect.Kind {
+	if typ.Kind() == reflect.Slice {
+		return typ.Elem().Kind()
+	}
+	return typ.Kind()
+}
+
+// setBasic sets the addressable variable, of a basic kind or a slice of
+// one, from the given text. The values of a slice are replaced by those in
+// the text, split using the separator.
+func setBasic(variable reflect.Value, text, separator string) error {
+	if variable.Kind() != reflect.Slice {
+		value, err := parseBasicValue(text, variable.Type())
+		if err != nil {
+			return err
+		}
+		variable.Set(value)
+		return nil
+	}
+	texts := []string{text}
+	if separator != "" {
+		texts = strings.Split(text, separator)
+	}
+	result := reflect.MakeSlice(variable.Type(), 0, len(texts))
+	for _, text := range texts {
+		value, err := parseBasicValue(text, variable.Type().Elem())
+		if err != nil {
+			return err
+		}
+		result = reflect.Append(result, value)
+	}
+	variable.Set(result)
+	return nil
+}
+
+// addFlag adds a flag for the given addressable variable using the flag
+// method for its type. Named basic types, EG: `type Port int`, use the
+// method for their underlying type.
+func (c *Command) addFlag(name, description string, variable reflect.Value) {
+	if kind := findValueType(variable.Type(), ""); kind != nil {
+		c.addValueField(name, description, "", "", "", variable, kind)
+		return
+	}
+	if variable.Kind() == reflect.Map {
+		c.addMapField(name, description, "", "", "", variable)
+		return
+	}
+	pointer := variable.Addr()
+	basic := basicTypes[variable.Kind()]
+	if variable.Kind() == reflect.Slice && basicTypes[variable.Type().Elem().Kind()] != nil {
+		elem := basicTypes[variable.Type().Elem().Kind()]
+		if variable.Type().Elem() != elem {
+			c.flags.Var(&namedSliceValue{target: variable}, name, description)
+			c.flagCount++
+			return
+		}
+		basic = reflect.SliceOf(elem)
+	}
+	if basic != nil && pointer.Type().ConvertibleTo(reflect.PtrTo(basic)) {
+		pointer = pointer.Convert(reflect.PtrTo(basic))
+	}
+	switch p := pointer.Interface().(type) {
+	case *bool:
+		c.BoolFlag(name, description, p)
+	case *[]bool:
+		c.BoolsFlag(name, description, p)
+	case *string:
+		c.StringFlag(name, description, p)
+	case *[]string:
+		c.StringsFlag(name, description, p)
+	case *int:
+		c.IntFlag(name, description, p)
+	case *[]int:
+		c.IntsFlag(name, description, p)
+	case *int8:
+		c.Int8Flag(name, description, p)
+	case *[]int8:
+		c.Int8sFlag(name, description, p)
+	case *int16:
+		c.Int16Flag(name, description, p)
+	case *[]int16:
+		c.Int16sFlag(name, description, p)
+	case *int32:
+		c.Int32Flag(name, description, p)
+	case *[]int32:
+		c.Int32sFlag(name, description, p)
+	case *int64:
+		c.Int64Flag(name, description, p)
+	case *[]int64:
+		c.Int64sFlag(name, description, p)
+	case *uint:
+		c.UintFlag(name, description, p)
+	case *[]uint:
+		c.UintsFlag(name, description, p)
+	case *uint8:
+		c.Uint8Flag(name, description, p)
+	case *[]uint8:
+		c.Uint8sFlag(name, description, p)
+	case *uint16:
+		c.Uint16Flag(name, description, p)
+	case *[]uint16:
+		c.Uint16sFlag(name, description, p)
+	case *uint32:
+		c.Uint32Flag(name, description, p)
+	case *[]uint32:
+		c.Uint32sFlag(name, description, p)
+	case *uint64:
+		c.UInt64Flag(name, description, p)
+	case *[]uint64:
+		c.Uint64sFlag(name, description, p)
+	case *float32:
+		c.Float32Flag(name, description, p)
+	case *[]float32:
+		c.Float32sFlag(name, description, p)
+	case *float64:
+		c.Float64Flag(name, description, p)
+	case *[]float64:
+		c.Float64sFlag(name, description, p)
+	default:
+		panic("Flag() does not support flags of type " + variable.Type().String() + ", it may be registered using RegisterType()")
+	}
+}
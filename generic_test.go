@@ -0,0 +1,199 @@
+package clir
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type port int
+
+type coordinate struct {
+	X, Y int
+}
+
+func (c coordinate) String() string { return fmt.Sprintf("%d,%d", c.X, c.Y) }
+
+// registerCoordinate registers the coordinate type for the duration of
+// the test
+func registerCoordinate(t *testing.T) {
+	RegisterType(func(text string) (coordinate, error) {
+		var result coordinate
+		_, err := fmt.Sscanf(text, "%d,%d", &result.X, &result.Y)
+		return result, err
+	})
+	t.Cleanup(func() {
+		valueTypesLock.Lock()
+		defer valueTypesLock.Unlock()
+		delete(valueTypes, reflect.TypeOf(coordinate{}))
+	})
+}
+
+func TestFlag(t *testing.T) {
+	registerCoordinate(t)
+	c := NewCli("app", "description", "0")
+	count := 1
+	var names []string
+	var listen port = 8080
+	var timeout time.Duration
+	var origin coordinate
+	var secret string
+	format := "json"
+	Flag(c, "count", "The count", &count, WithShort("c"))
+	Flag(c, "name", "The names", &names)
+	serve := c.NewSubCommand("serve", "Serve the app")
+	Flag(serve, "port", "The port", &listen, WithEnv("APP_PORT"))
+	Flag(serve, "timeout", "The timeout", &timeout, WithRequired())
+	Flag(serve, "origin", "The origin", &origin)
+	Flag(serve, "secret", "The secret", &secret, WithHidden())
+	Flag(serve, "format", "The format", &format, WithEnum("json", "yaml"))
+	serve.Action(func() error { return nil })
+
+	t.Setenv("APP_PORT", "9000")
+	e := c.Run("-c", "3", "--name", "a", "--name", "b", "serve", "--timeout", "1m", "--origin", "3,4", "--secret", "s", "--format", "yaml")
+	if e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if count != 3 || !reflect.DeepEqual(names, []string{"a", "b"}) || listen != 9000 || timeout != time.Minute {
+		t.Errorf("unexpected values: %d, %v, %d, %v", count, names, listen, timeout)
+	}
+	if origin != (coordinate{3, 4}) || secret != "s" || format != "yaml" {
+		t.Errorf("unexpected values: %v, %q, %q", origin, secret, format)
+	}
+
+	c.ResetFlags()
+	var missing *MissingRequiredError
+	if e := c.Run("serve"); !errors.As(e, &missing) {
+		t.Errorf("expected a missing required flag error, got %v", e)
+	}
+	c.ResetFlags()
+	if e := c.Run("serve", "--timeout", "1s", "--format", "xml"); e == nil || !strings.Contains(e.Error(), "must be one of: json, yaml") {
+		t.Errorf("expected an invalid choice error, got %v", e)
+	}
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if e := c.Run("serve", "--help"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if strings.Contains(output.String(), "secret") {
+		t.Errorf("expected hidden flag not to be shown, got:\n%s", output.String())
+	}
+	if !strings.Contains(output.String(), "  --port int\n    \tThe port (default 8080) (env: APP_PORT)\n") {
+		t.Errorf("expected port flag in help, got:\n%s", output.String())
+	}
+}
+
+func TestFlag_NamedSlice(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	ports := []port{8080}
+	Flag(c, "ports", "The ports", &ports, WithShort("p"))
+	c.Action(func() error { return nil })
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if e := c.Run("--help"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !strings.Contains(output.String(), "The ports (default [8080])") {
+		t.Errorf("expected the default in help, got:\n%s", output.String())
+	}
+
+	if e := c.Run("-p", "80", "--ports", "443"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !reflect.DeepEqual(ports, []port{80, 443}) {
+		t.Errorf("unexpected ports: %v", ports)
+	}
+
+	c.ResetFlags()
+	if !reflect.DeepEqual(ports, []port{8080}) {
+		t.Errorf("expected the default ports to be restored, got %v", ports)
+	}
+	var invalidValue *InvalidValueError
+	if e := c.Run("--ports", "http"); !errors.As(e, &invalidValue) || invalidValue.Error() != `invalid value "http" for flag --ports: parse error` {
+		t.Errorf("expected a parse error, got %v", e)
+	}
+}
+
+func TestAddFlags_NamedTypes(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	flags := &struct {
+		Port   port      `name:"port" short:"p" default:"8080"`
+		Ports  []port    `name:"ports" default:"80,443" sep:","`
+		Ratios []float64 `name:"ratio" default:"0.5"`
+		Hosts  []port    `pos:"1" name:"hosts" sep:","`
+	}{}
+	c.AddFlags(flags).Action(func() error { return nil })
+	if flags.Port != 8080 || !reflect.DeepEqual(flags.Ports, []port{80, 443}) || !reflect.DeepEqual(flags.Ratios, []float64{0.5}) {
+		t.Errorf("unexpected defaults: %+v", flags)
+	}
+
+	if e := c.Run("-p", "9000", "--ports", "8443", "1,2"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Port != 9000 || !reflect.DeepEqual(flags.Ports, []port{8443}) || !reflect.DeepEqual(flags.Hosts, []port{1, 2}) {
+		t.Errorf("unexpected values: %+v", flags)
+	}
+
+	c.ResetFlags()
+	var invalidValue *InvalidValueError
+	if e := c.Run("1,x"); !errors.As(e, &invalidValue) || invalidValue.Arg != "hosts" {
+		t.Errorf("expected an invalid argument error, got %v", e)
+	}
+
+	defer func() {
+		if r := recover(); r != "Invalid default value for int flag" {
+			t.Errorf("expected panic for an invalid default, got %v", r)
+		}
+	}()
+	NewCli("app", "description", "0").AddFlags(&struct {
+		Ports []port `name:"ports" default:"http"`
+	}{})
+}
+
+func TestFlag_Unsupported(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "does not support flags of type chan int") {
+			t.Errorf("expected panic for unsupported type, got %v", r)
+		}
+	}()
+	var values chan int
+	Flag(NewCli("app", "description", "0"), "values", "The values", &values)
+}
+
+func TestRegisterType_AddFlags(t *testing.T) {
+	registerCoordinate(t)
+	flags := &struct {
+		Origin coordinate   `name:"origin" default:"1,2"`
+		Points []coordinate `name:"point"`
+	}{}
+	c := NewCli("app", "description", "0").AddFlags(flags)
+	c.Action(func() error { return nil })
+	if e := c.Run("--point", "0,0", "--point", "5,6"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Origin != (coordinate{1, 2}) || !reflect.DeepEqual(flags.Points, []coordinate{{0, 0}, {5, 6}}) {
+		t.Errorf("unexpected values: %+v", flags)
+	}
+}
+
+func TestBasicValue_Range(t *testing.T) {
+	var small int8
+	var sizes []uint16
+	c := NewCli("app", "description", "0")
+	c.rootCommand.Int8Flag("small", "A small number", &small).Uint16sFlag("size", "The sizes", &sizes)
+	tests := map[string][]string{
+		`invalid value "300" for flag --small: value out of range`: {"--small", "300"},
+		`invalid value "-1" for flag --size: parse error`:          {"--size", "-1"},
+	}
+	for message, args := range tests {
+		if e := c.Run(args...); e == nil || !strings.Contains(e.Error(), message) {
+			t.Errorf("%v: expected %q, got %v", args, message, e)
+		}
+	}
+}
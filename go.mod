@@ -1,3 +1,3 @@
 module github.com/leaanthony/clir
 
-go 1.12
+go 1.18
@@ -0,0 +1,219 @@
+package clir
+
+import (
+	"strings"
+)
+
+// FlagGroupRule is the constraint a flag group places on its flags
+type FlagGroupRule int
+
+const (
+	// RuleExactlyOne requires exactly one of the flags to be set
+	RuleExactlyOne FlagGroupRule = iota
+	// RuleAtMostOne allows at most one of the flags to be set
+	RuleAtMostOne
+	// RuleRequires requires all the flags to be set if a flag is set
+	RuleRequires
+)
+
+// flagGroup is a constraint on which of a command's flags may be set
+type flagGroup struct {
+	rule FlagGroupRule
+	// flag is the flag that requires the others, for RuleRequires
+	flag  string
+	flags []string
+	// tag is the name given by the xor or exclusive struct tag
+	tag string
+}
+
+// pendingRequires is a requires struct tag that is resolved once all the
+// fields of the struct have been added
+type pendingRequires struct {
+	field    string
+	flag     string
+	required []string
+}
+
+// ExactlyOneOf - Requires exactly one of the named flags to be set,
+// EG: `cmd.ExactlyOneOf("file", "url", "stdin")`
+func (c *Command) ExactlyOneOf(names ...string) *Command {
+	c.addFlagGroup("ExactlyOneOf", &flagGroup{rule: RuleExactlyOne, flags: names})
+	return c
+}
+
+// AtMostOneOf - Allows at most one of the named flags to be set,
+// EG: `cmd.AtMostOneOf("json", "yaml")`
+func (c *Command) AtMostOneOf(names ...string) *Command {
+	c.addFlagGroup("AtMostOneOf", &flagGroup{rule: RuleAtMostOne, flags: names})
+	return c
+}
+
+// Requires - Requires the given flags to be set whenever the named flag is
+// set, EG: `cmd.Requires("key", "cert")`
+func (c *Command) Requires(name string, required ...string) *Command {
+	if c.flags.Lookup(name) == nil {
+		panic("Requires() requires flag '" + name + "' to be defined first")
+	}
+	if len(required) == 0 {
+		panic("Requires() requires at least one flag to be required by '" + name + "'")
+	}
+	c.addFlagGroup("Requires", &flagGroup{rule: RuleRequires, flag: name, flags: required})
+	return c
+}
+
+// addFlagGroup adds the group to the command, panicking if any of its
+// flags have not been defined
+func (c *Command) addFlagGroup(method string, group *flagGroup) {
+	if group.rule != RuleRequires && len(group.flags) < 2 {
+		panic(method + "() requires at least two flags")
+	}
+	for _, name := range group.flags {
+		if c.flags.Lookup(name) == nil {
+			panic(method + "() requires flag '" + name + "' to be defined first")
+		}
+	}
+	c.flagGroups = append(c.flagGroups, group)
+}
+
+// addTagGroup adds the flag to the group named by an xor or exclusive
+// struct tag, creating the group if needed
+func (c *Command) addTagGroup(rule FlagGroupRule, tag, name string) {
+	for _, group := range c.flagGroups {
+		if group.rule == rule && group.tag == tag {
+			group.flags = append(group.flags, name)
+			return
+		}
+	}
+	c.flagGroups = append(c.flagGroups, &flagGroup{rule: rule, flags: []string{name}, tag: tag})
+}
+
+// addPendingRequires adds the groups given by requires struct tags,
+// panicking if they name a flag that has not been defined
+func (c *Command) addPendingRequires() {
+	pending := c.pendingRequires
+	c.pendingRequires = nil
+	for _, requires := range pending {
+		for _, name := range requires.required {
+			if c.flags.Lookup(name) == nil {
+				panic("Invalid requires tag on field " + requires.field + ": flag '" + name + "' is not defined")
+			}
+		}
+		c.flagGroups = append(c.flagGroups, &flagGroup{rule: RuleRequires, flag: requires.flag, flags: requires.required})
+	}
+}
+
+// checkTagGroups panics if a group named by an xor or exclusive struct tag
+// has fewer than two flags, as the group would have no effect or could
+// never be satisfied
+func (c *Command) checkTagGroups() {
+	for _, group := range c.flagGroups {
+		if group.tag == "" || len(group.flags) >= 2 {
+			continue
+		}
+		tag := "xor"
+		if group.rule == RuleAtMostOne {
+			tag = "exclusive"
+		}
+		panic("Invalid " + tag + " tag on flag '" + group.flags[0] + "': group '" + group.tag + "' requires at least two flags")
+	}
+}
+
+// IsSet - Returns true if the named flag was set on the command line,
+// through an environment variable or by the config file, rather than
+// holding its default value
+func (c *Command) IsSet(name string) bool {
+	return c.isFlagSet(name)
+}
+
+// checkFlagGroups returns an error for the first flag group whose
+// constraint is not met
+func (c *Command) checkFlagGroups() error {
+	for _, group := range c.flagGroups {
+		var set []string
+		for _, name := range group.flags {
+			if c.isFlagSet(name) {
+				set = append(set, name)
+			}
+		}
+		failed := false
+		switch group.rule {
+		case RuleExactlyOne:
+			failed = len(set) != 1
+		case RuleAtMostOne:
+			failed = len(set) > 1
+		case RuleRequires:
+			failed = c.isFlagSet(group.flag) && len(set) < len(group.flags)
+		}
+		if failed {
+			return &FlagGroupError{Command: c.commandPath, Rule: group.rule, Flag: group.flag, Flags: group.flags, Set: set}
+		}
+	}
+	return nil
+}
+
+// helpFlagGroups returns the help model of the command's flag groups
+func (c *Command) helpFlagGroups() []*HelpFlagGroup {
+	var result []*HelpFlagGroup
+	for _, group := range c.flagGroups {
+		result = append(result, &HelpFlagGroup{Rule: group.rule, Flag: group.flag, Flags: group.flags})
+	}
+	return result
+}
+
+// HelpFlagGroup is the help model of a flag group
+type HelpFlagGroup struct {
+	Rule FlagGroupRule
+	// Flag is the flag that requires the others, for RuleRequires
+	Flag  string
+	Flags []string
+}
+
+// Description - Returns the constraint of the group in words,
+// EG: `Exactly one of: --file, --url`
+func (g *HelpFlagGroup) Description() string {
+	flags := flagNames(g.Flags)
+	switch g.Rule {
+	case RuleExactlyOne:
+		return "Exactly one of: " + flags
+	case RuleAtMostOne:
+		return "At most one of: " + flags
+	}
+	return "--" + g.Flag + " requires: " + flags
+}
+
+// flagNames returns the names of the flags as they are given on the
+// command line, EG: `--file, --url`
+func flagNames(names []string) string {
+	result := make([]string, len(names))
+	for index, name := range names {
+		result[index] = "--" + name
+	}
+	return strings.Join(result, ", ")
+}
+
+// ExactlyOneOf - Requires exactly one of the named root command flags to be
+// set.
+func (c *Cli) ExactlyOneOf(names ...string) *Cli {
+	c.rootCommand.ExactlyOneOf(names...)
+	return c
+}
+
+// AtMostOneOf - Allows at most one of the named root command flags to be
+// set.
+func (c *Cli) AtMostOneOf(names ...string) *Cli {
+	c.rootCommand.AtMostOneOf(names...)
+	return c
+}
+
+// Requires - Requires the given root command flags to be set whenever the
+// named flag is set.
+func (c *Cli) Requires(name string, required ...string) *Cli {
+	c.rootCommand.Requires(name, required...)
+	return c
+}
+
+// IsSet - Returns true if the named root command flag was set rather than
+// holding its default value.
+func (c *Cli) IsSet(name string) bool {
+	return c.rootCommand.IsSet(name)
+}
@@ -0,0 +1,123 @@
+package clir
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type sourceFlags struct {
+	File  string `name:"file" xor:"source"`
+	URL   string `name:"url" xor:"source"`
+	JSON  bool   `name:"json" exclusive:"format"`
+	YAML  bool   `name:"yaml" exclusive:"format"`
+	Key   string `name:"key" requires:"cert"`
+	Cert  string `name:"cert"`
+	Stdin bool   `name:"stdin"`
+}
+
+func TestCommand_FlagGroups(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	flags := &sourceFlags{}
+	var ran bool
+	load := c.NewSubCommand("load", "Load data")
+	load.AddFlags(flags).Action(func() error {
+		ran = true
+		return nil
+	})
+	load.ExactlyOneOf("file", "stdin")
+
+	c.ResetFlags()
+	if e := c.Run("load", "--file", "data.json", "--json", "--key", "a", "--cert", "b"); e != nil || !ran {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !load.IsSet("file") || load.IsSet("url") || !load.IsSet("json") {
+		t.Errorf("unexpected flags reported as set")
+	}
+
+	tests := []struct {
+		args    []string
+		rule    FlagGroupRule
+		message string
+	}{
+		{[]string{"load", "--stdin"}, RuleExactlyOne, "exactly one of --file, --url is required"},
+		{[]string{"load", "--file", "a", "--url", "b"}, RuleExactlyOne, "only one of --file, --url may be given, got --file, --url"},
+		{[]string{"load", "--file", "a", "--json", "--yaml"}, RuleAtMostOne, "only one of --json, --yaml may be given, got --json, --yaml"},
+		{[]string{"load", "--file", "a", "--key", "k"}, RuleRequires, "--key requires --cert"},
+		{[]string{"load", "--url", "a"}, RuleExactlyOne, "exactly one of --file, --stdin is required"},
+	}
+	for _, test := range tests {
+		c.ResetFlags()
+		e := c.Run(test.args...)
+		var group *FlagGroupError
+		if !errors.As(e, &group) || group.Rule != test.rule || group.Error() != test.message {
+			t.Errorf("%v: expected %q, got %v", test.args, test.message, e)
+		}
+		if ExitCode(e) != 2 {
+			t.Errorf("%v: expected exit code 2, got %d", test.args, ExitCode(e))
+		}
+	}
+}
+
+func TestCommand_FlagGroupsHelp(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	c.AddFlags(&sourceFlags{}).AtMostOneOf("stdin", "url")
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if e := c.Run("--help"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	expected := "Flag groups:\n\n  Exactly one of: --file, --url\n  At most one of: --json, --yaml\n  --key requires: --cert\n  At most one of: --stdin, --url\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected help to contain %q, got:\n%s", expected, output.String())
+	}
+
+	var markdown bytes.Buffer
+	if e := c.WriteMarkdown(&markdown); e != nil || !strings.Contains(markdown.String(), "## Flag Groups\n\n* Exactly one of: --file, --url\n") {
+		t.Errorf("expected flag groups in the Markdown page, got %v:\n%s", e, markdown.String())
+	}
+}
+
+func TestCommand_FlagGroupsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(c *Cli)
+	}{
+		{"undefined flag", func(c *Cli) {
+			var file string
+			c.StringFlag("file", "The file", &file).ExactlyOneOf("file", "url")
+		}},
+		{"single flag", func(c *Cli) {
+			var file string
+			c.StringFlag("file", "The file", &file).AtMostOneOf("file")
+		}},
+		{"undefined requires tag", func(c *Cli) {
+			c.AddFlags(&struct {
+				Key string `name:"key" requires:"cert"`
+			}{})
+		}},
+		{"single flag xor tag", func(c *Cli) {
+			c.AddFlags(&struct {
+				File string `name:"file" xor:"source"`
+				URL  string `name:"url" xor:"input"`
+			}{})
+		}},
+		{"single flag exclusive tag", func(c *Cli) {
+			c.AddFlags(&struct {
+				JSON bool `name:"json" exclusive:"format"`
+			}{})
+		}},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%s: expected panic", test.name)
+				}
+			}()
+			test.setup(NewCli("app", "description", "0"))
+		}()
+	}
+}
@@ -0,0 +1,334 @@
+package clir
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// Help is the structured model of a command's help text. It is given to
+// the HelpRenderer when help is printed.
+type Help struct {
+	// Banner is the application banner, EG: `app v1.0.0 - My app`
+	Banner string
+	// Name is the name of the command
+	Name string
+	// Aliases are the alternative names of the command
+	Aliases []string
+	// Path is the full command path, EG: `app db migrate`
+	Path string
+	// IsRoot is true for the application's root command
+	IsRoot           bool
+	ShortDescription string
+	LongDescription  string
+	// Usage is the usage line, EG: `app db migrate [flags] <name>`
+	Usage string
+	// Commands are the subcommands that are not hidden
+	Commands []*HelpCommand
+	// Flags are the flags defined by the command itself
+	Flags []*HelpFlag
+	// GlobalFlags are the persistent flags inherited from the command's
+	// ancestors
+	GlobalFlags []*HelpFlag
+	// FlagGroups are the constraints on which of the command's flags may
+	// be set
+	FlagGroups []*HelpFlagGroup
+}
+
+// HelpCommand is the help model of a subcommand
+type HelpCommand struct {
+	Name             string
+	Aliases          []string
+	ShortDescription string
+	// IsDefault is true if the command is the application's default command
+	IsDefault bool
+}
+
+// displayName returns the name of the command followed by its aliases,
+// EG: `remove (rm, del)`
+func (c *HelpCommand) displayName() string {
+	if len(c.Aliases) == 0 {
+		return c.Name
+	}
+	return c.Name + " (" + strings.Join(c.Aliases, ", ") + ")"
+}
+
+// HelpFlag is the help model of a flag
+type HelpFlag struct {
+	Name  string
+	Short string
+	// Type is the name of the value the flag takes, EG: `string`.
+	// It is blank for boolean flags.
+	Type  string
+	Usage string
+	// Default is the default value, formatted for display, or blank if it
+	// is the zero value
+	Default string
+	// Env is the environment variable the flag is bound to
+	Env      string
+	Required bool
+	// Choices are the values the flag accepts, if it is restricted to a set
+	Choices []string
+}
+
+// Names - Returns the ways the flag may be given, EG: `-v, --verbose`
+func (f *HelpFlag) Names() string {
+	if f.Short != "" {
+		return "-" + f.Short + ", --" + f.Name
+	}
+	return "--" + f.Name
+}
+
+// HelpRenderer renders the help text for a command
+type HelpRenderer interface {
+	RenderHelp(w io.Writer, help *Help) error
+}
+
+// HelpRendererFunc is a function that implements HelpRenderer
+type HelpRendererFunc func(w io.Writer, help *Help) error
+
+// RenderHelp - Calls the function
+func (fn HelpRendererFunc) RenderHelp(w io.Writer, help *Help) error {
+	return fn(w, help)
+}
+
+// helpTemplateFuncs are the functions available to help templates
+var helpTemplateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"pad": func(width int, text string) string {
+		if len(text) >= width {
+			return text
+		}
+		return text + strings.Repeat(" ", width-len(text))
+	},
+	"indent": func(spaces int, text string) string {
+		prefix := strings.Repeat(" ", spaces)
+		return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+	},
+}
+
+// templateRenderer renders help using a text/template
+type templateRenderer struct {
+	template *template.Template
+}
+
+// RenderHelp - Executes the template with the help model
+func (t *templateRenderer) RenderHelp(w io.Writer, help *Help) error {
+	return t.template.Execute(w, help)
+}
+
+// HelpTemplate - Returns a HelpRenderer that renders help using the given
+// text/template, which is executed with a *Help. The functions join, upper,
+// lower, pad (width, text) and indent (spaces, text) are available.
+// It panics if the template is invalid.
+func HelpTemplate(text string) HelpRenderer {
+	return &templateRenderer{
+		template: template.Must(template.New("help").Funcs(helpTemplateFuncs).Parse(text)),
+	}
+}
+
+// SetHelpRenderer - Sets the renderer used to print the help of this command
+// and its descendants, overriding the renderer set on the application
+func (c *Command) SetHelpRenderer(renderer HelpRenderer) *Command {
+	c.helpRenderer = renderer
+	return c
+}
+
+// renderer returns the help renderer for the command: its own, its closest
+// ancestor's, the application's or the default, in that order
+func (c *Command) renderer() HelpRenderer {
+	for command := c; command != nil; command = command.parent {
+		if command.helpRenderer != nil {
+			return command.helpRenderer
+		}
+	}
+	if c.app != nil && c.app.helpRenderer != nil {
+		return c.app.helpRenderer
+	}
+	return HelpRendererFunc(renderDefaultHelp)
+}
+
+// Help - Returns the structured help model of the command
+func (c *Command) Help() *Help {
+	c.mergePersistentFlags()
+	result := &Help{
+		Name:             c.name,
+		Aliases:          c.aliases,
+		Path:             c.commandPath,
+		IsRoot:           c.parent == nil,
+		ShortDescription: c.shortdescription,
+		LongDescription:  c.longdescription,
+		Usage:            c.usage(),
+	}
+	if c.app != nil {
+		result.Banner = c.app.bannerFunction(c.app)
+	}
+	for _, subcommand := range c.visibleSubCommands() {
+		result.Commands = append(result.Commands, &HelpCommand{
+			Name:             subcommand.name,
+			Aliases:          subcommand.aliases,
+			ShortDescription: subcommand.shortdescription,
+			IsDefault:        subcommand.isDefaultCommand(),
+		})
+	}
+	result.Flags, result.GlobalFlags = c.helpFlags()
+	result.FlagGroups = c.helpFlagGroups()
+	return result
+}
+
+// helpFlags returns the flags defined by the command itself and the global
+// flags inherited from its ancestors
+func (c *Command) helpFlags() (local []*HelpFlag, global []*HelpFlag) {
+	if c.flags == nil {
+		return nil, nil
+	}
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if !c.inheritedFlags[f.Name] && !c.isFlagHidden(f.Name) {
+			local = append(local, c.newHelpFlag(f, c.flagDetails[f.Name]))
+		}
+	})
+	globalFlags, owners := c.globalFlags()
+	for index, f := range globalFlags {
+		owner := owners[index]
+		if owner.persistentFlags.isFlagHidden(f.Name) {
+			continue
+		}
+		global = append(global, owner.newHelpFlag(f, owner.persistentFlags.flagDetails[f.Name]))
+	}
+	return local, global
+}
+
+// newHelpFlag returns the help model of the given flag, which is defined
+// by the command c
+func (c *Command) newHelpFlag(f *flag.Flag, detail *flagDetail) *HelpFlag {
+	unwrapped := *f
+	unwrapped.Value = underlyingValue(f)
+	typeName, usage := flag.UnquoteUsage(&unwrapped)
+	if named, ok := unwrapped.Value.(typeNamer); ok && typeName == "value" {
+		typeName = named.typeName()
+	}
+	result := &HelpFlag{
+		Name:  f.Name,
+		Type:  typeName,
+		Usage: usage,
+		Env:   c.envVar(f.Name, detail),
+	}
+	if !isZeroValue(f) {
+		if reflect.Indirect(reflect.ValueOf(unwrapped.Value)).Kind() == reflect.String {
+			result.Default = fmt.Sprintf("%q", f.DefValue)
+		} else {
+			result.Default = f.DefValue
+		}
+	}
+	if detail != nil {
+		result.Short = detail.short
+		result.Required = detail.required
+		result.Choices = detail.choices
+	}
+	return result
+}
+
+// typeNamer is implemented by flag values that name the type of value
+// they take for the help text, EG: `duration`
+type typeNamer interface {
+	typeName() string
+}
+
+// renderDefaultHelp renders help in clir's standard layout
+func renderDefaultHelp(w io.Writer, help *Help) error {
+	var b strings.Builder
+	b.WriteString(help.Banner + "\n\n")
+
+	// Ignore root command
+	if !help.IsRoot {
+		title := help.Path
+		if help.ShortDescription != "" {
+			title += " - " + help.ShortDescription
+		}
+		b.WriteString(title + "\n")
+	}
+	b.WriteString("Usage: " + help.Usage + "\n\n")
+	if help.LongDescription != "" {
+		b.WriteString(help.LongDescription + "\n\n")
+	}
+	if len(help.Commands) > 0 {
+		longest := 0
+		for _, command := range help.Commands {
+			if len(command.displayName()) > longest {
+				longest = len(command.displayName())
+			}
+		}
+		b.WriteString("Available commands:\n\n")
+		for _, command := range help.Commands {
+			name := command.displayName()
+			spacer := strings.Repeat(" ", 3+longest-len(name))
+			isDefault := ""
+			if command.IsDefault {
+				isDefault = "[default]"
+			}
+			fmt.Fprintf(&b, "   %s%s%s %s\n", name, spacer, command.ShortDescription, isDefault)
+		}
+		b.WriteString("\n")
+	}
+	if len(help.Flags) > 0 {
+		b.WriteString("Flags:\n\n")
+		for _, f := range help.Flags {
+			writeFlagDefault(&b, f)
+		}
+	}
+	if len(help.GlobalFlags) > 0 {
+		if len(help.Flags) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("Global flags:\n\n")
+		for _, f := range help.GlobalFlags {
+			writeFlagDefault(&b, f)
+		}
+	}
+	if len(help.FlagGroups) > 0 {
+		b.WriteString("\nFlag groups:\n\n")
+		for _, group := range help.FlagGroups {
+			b.WriteString("  " + group.Description() + "\n")
+		}
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFlagDefault writes the given flag in the same format as
+// flag.PrintDefaults, with the names given by HelpFlag.Names, EG:
+// `-v, --verbose` or `--name`, and the environment variable it is bound to.
+func writeFlagDefault(b *strings.Builder, f *HelpFlag) {
+	var line strings.Builder
+	line.WriteString("  " + f.Names())
+	if len(f.Type) > 0 {
+		line.WriteString(" " + f.Type)
+	}
+	// Boolean flags of one ASCII letter are so common we
+	// treat them specially, putting their usage on the same line.
+	if line.Len() <= len("  --x") {
+		line.WriteString("\t")
+	} else {
+		line.WriteString("\n    \t")
+	}
+	line.WriteString(strings.ReplaceAll(f.Usage, "\n", "\n    \t"))
+	if len(f.Choices) > 0 {
+		fmt.Fprintf(&line, " (one of: %s)", strings.Join(f.Choices, ", "))
+	}
+	if f.Default != "" {
+		fmt.Fprintf(&line, " (default %s)", f.Default)
+	}
+	if f.Env != "" {
+		fmt.Fprintf(&line, " (env: %s)", f.Env)
+	}
+	if f.Required {
+		line.WriteString(" (required)")
+	}
+	b.WriteString(line.String() + "\n")
+}
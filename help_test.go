@@ -0,0 +1,85 @@
+package clir
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCommand_Help(t *testing.T) {
+	c := newDocsCli()
+	migrate := c.rootCommand.subCommandsMap["db"].subCommandsMap["migrate"]
+	help := migrate.Help()
+	if help.Path != "app db migrate" || help.Name != "migrate" || help.IsRoot {
+		t.Errorf("unexpected command details: %+v", help)
+	}
+	if help.Banner != "app v1.0.0 - An example app" {
+		t.Errorf("expected banner, got %q", help.Banner)
+	}
+	if help.Usage != "app db migrate --target <string> [flags]" {
+		t.Errorf("expected usage, got %q", help.Usage)
+	}
+	var steps *HelpFlag
+	for _, f := range help.Flags {
+		if f.Name == "steps" {
+			steps = f
+		}
+	}
+	if steps == nil || steps.Short != "s" || steps.Type != "int" || steps.Default != "5" || steps.Names() != "-s, --steps" {
+		t.Errorf("unexpected flag details: %+v", steps)
+	}
+	if len(help.GlobalFlags) != 1 || help.GlobalFlags[0].Env != "APP_CONFIG" {
+		t.Errorf("expected the config global flag, got %+v", help.GlobalFlags)
+	}
+
+	root := c.rootCommand.Help()
+	if !root.IsRoot || len(root.Commands) != 1 || root.Commands[0].Name != "db" {
+		t.Errorf("expected only visible commands, got %+v", root.Commands)
+	}
+}
+
+func TestCli_SetOutput(t *testing.T) {
+	var buffer bytes.Buffer
+	c := newDocsCli().SetOutput(&buffer)
+	if err := c.Run("db", "--help"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := "app v1.0.0 - An example app\n\napp db - Database commands\nUsage: app db [command] [flags]\n"
+	if !strings.HasPrefix(buffer.String(), expected) {
+		t.Errorf("expected help to start with %q, got:\n%s", expected, buffer.String())
+	}
+}
+
+func TestCli_SetHelpRenderer(t *testing.T) {
+	var buffer bytes.Buffer
+	c := newDocsCli().SetOutput(&buffer).
+		SetHelpRenderer(HelpTemplate(`{{.Path}}:{{range .Commands}} {{.Name}}{{end}}{{range .Flags}} {{.Names}}{{end}}`))
+	c.PrintHelp()
+	if buffer.String() != "app: db --help" {
+		t.Errorf("expected help from template, got %q", buffer.String())
+	}
+
+	// Commands override the application's renderer, as do their descendants
+	db := c.rootCommand.subCommandsMap["db"]
+	db.SetHelpRenderer(HelpRendererFunc(func(w io.Writer, help *Help) error {
+		_, err := io.WriteString(w, "terse "+help.Name)
+		return err
+	}))
+	buffer.Reset()
+	if err := c.Run("db", "migrate", "--help"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buffer.String() != "terse migrate" {
+		t.Errorf("expected help from the db renderer, got %q", buffer.String())
+	}
+}
+
+func TestHelpTemplate_Invalid(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic for invalid template")
+		}
+	}()
+	HelpTemplate("{{.Path")
+}
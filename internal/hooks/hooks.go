@@ -0,0 +1,11 @@
+// Package hooks gives the clirtest package access to parts of clir that
+// are not exported.
+package hooks
+
+import (
+	"context"
+)
+
+// RunArgs runs the given *clir.Cli with exactly the given arguments, which
+// may be empty, rather than falling back to os.Args. It is set by clir.
+var RunArgs func(ctx context.Context, cli interface{}, args []string) error
@@ -0,0 +1,198 @@
+package clir
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnableManPages - Adds a hidden `gen-man [dir]` subcommand that writes a
+// man page for each command to the given directory, which defaults to the
+// current directory
+func (c *Cli) EnableManPages() *Cli {
+	command := c.NewSubCommand("gen-man", "Generate man pages")
+	command.LongDescription("Writes a man page for each command to the given directory, EG: " + c.Name() + ".1")
+	command.Hidden()
+	command.internal = true
+	command.Action(func() error {
+		dir := "."
+		if command.flags.NArg() > 0 {
+			dir = command.flags.Arg(0)
+		}
+		return c.WriteManPages(dir)
+	})
+	return c
+}
+
+// WriteManPage - Writes the man page for the application to the given writer
+func (c *Cli) WriteManPage(w io.Writer) error {
+	return c.rootCommand.WriteManPage(w)
+}
+
+// WriteManPages - Writes a man page for each command that isn't hidden to
+// the given directory. Pages are named after the command path, EG:
+// `app-db-migrate.1`
+func (c *Cli) WriteManPages(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return c.rootCommand.walkVisible(func(command *Command) error {
+		return writeFile(filepath.Join(dir, command.pageName()+".1"), command.WriteManPage)
+	})
+}
+
+// WriteManPage - Writes the man page for the command, in roff format, to the
+// given writer
+func (c *Command) WriteManPage(w io.Writer) error {
+	c.mergePersistentFlags()
+	appName := c.app.Name()
+	var b strings.Builder
+
+	fmt.Fprintf(&b, ".TH \"%s\" \"1\" \"\" \"%s\" \"%s Manual\"\n",
+		roffEscape(strings.ToUpper(c.pageName())), roffEscape(strings.TrimSpace(appName+" "+c.app.Version())), roffEscape(appName))
+
+	b.WriteString(".SH NAME\n")
+	b.WriteString(roffEscape(c.pageName()))
+	if c.shortdescription != "" {
+		b.WriteString(" \\- " + roffEscape(c.shortdescription))
+	}
+	b.WriteString("\n")
+
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, "\\fB%s\\fR%s\n", roffEscape(c.commandPath), roffEscape(strings.TrimPrefix(c.usage(), c.commandPath)))
+
+	if len(c.aliases) > 0 {
+		b.WriteString(".SH ALIASES\n")
+		fmt.Fprintf(&b, "\\fB%s\\fR\n", strings.Join(roffEscapeAll(c.aliases), "\\fR, \\fB"))
+	}
+
+	if description := c.description(); description != "" {
+		b.WriteString(".SH DESCRIPTION\n")
+		b.WriteString(roffParagraphs(description))
+	}
+
+	local, global := c.helpFlags()
+	if len(local) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		writeRoffFlags(&b, local)
+	}
+	if len(global) > 0 {
+		b.WriteString(".SH GLOBAL OPTIONS\n")
+		writeRoffFlags(&b, global)
+	}
+	if groups := c.helpFlagGroups(); len(groups) > 0 {
+		b.WriteString(".SH FLAG GROUPS\n")
+		for index, group := range groups {
+			if index > 0 {
+				b.WriteString(".br\n")
+			}
+			b.WriteString(roffEscape(group.Description()) + "\n")
+		}
+	}
+
+	subcommands := c.visibleSubCommands()
+	if len(subcommands) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, subcommand := range subcommands {
+			names := []string{subcommand.name}
+			names = append(names, subcommand.aliases...)
+			fmt.Fprintf(&b, ".TP\n\\fB%s\\fR\n", strings.Join(roffEscapeAll(names), "\\fR, \\fB"))
+			if subcommand.shortdescription != "" {
+				b.WriteString(roffEscape(subcommand.shortdescription) + "\n.br\n")
+			}
+			fmt.Fprintf(&b, "See \\fB%s\\fR(1).\n", roffEscape(subcommand.pageName()))
+		}
+	}
+
+	var seeAlso []string
+	if c.parent != nil {
+		seeAlso = append(seeAlso, c.parent.pageName())
+	}
+	for _, subcommand := range subcommands {
+		seeAlso = append(seeAlso, subcommand.pageName())
+	}
+	if len(seeAlso) > 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		for index, page := range seeAlso {
+			if index > 0 {
+				b.WriteString(",\n")
+			}
+			fmt.Fprintf(&b, "\\fB%s\\fR(1)", roffEscape(page))
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRoffFlags writes the given flags as a roff tagged paragraph list
+func writeRoffFlags(b *strings.Builder, flags []*HelpFlag) {
+	for _, f := range flags {
+		b.WriteString(".TP\n")
+		if f.Short != "" {
+			fmt.Fprintf(b, "\\fB\\-%s\\fR, ", roffEscape(f.Short))
+		}
+		fmt.Fprintf(b, "\\fB\\-\\-%s\\fR", roffEscape(f.Name))
+		if f.Type != "" {
+			fmt.Fprintf(b, " \\fI%s\\fR", roffEscape(f.Type))
+		}
+		b.WriteString("\n")
+		if f.Usage != "" {
+			b.WriteString(roffEscape(f.Usage) + "\n")
+		}
+		var notes []string
+		if f.Required {
+			notes = append(notes, "Required.")
+		}
+		if len(f.Choices) > 0 {
+			notes = append(notes, "One of: "+strings.Join(f.Choices, ", ")+".")
+		}
+		if f.Default != "" {
+			notes = append(notes, "Default: "+f.Default+".")
+		}
+		if f.Env != "" {
+			notes = append(notes, "Environment: "+f.Env+".")
+		}
+		if len(notes) > 0 {
+			b.WriteString(".br\n" + roffEscape(strings.Join(notes, " ")) + "\n")
+		}
+	}
+}
+
+// roffParagraphs formats the given text as roff paragraphs, which are
+// separated by blank lines
+func roffParagraphs(text string) string {
+	var b strings.Builder
+	for index, paragraph := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if index > 0 {
+			b.WriteString(".PP\n")
+		}
+		b.WriteString(roffEscape(strings.TrimSpace(paragraph)) + "\n")
+	}
+	return b.String()
+}
+
+// roffEscape escapes the given text so it is not interpreted as roff
+// requests or escape sequences
+func roffEscape(text string) string {
+	text = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(text)
+	lines := strings.Split(text, "\n")
+	for index, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[index] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// roffEscapeAll escapes each of the given strings for roff
+func roffEscapeAll(texts []string) []string {
+	result := make([]string, len(texts))
+	for index, text := range texts {
+		result[index] = roffEscape(text)
+	}
+	return result
+}
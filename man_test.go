@@ -0,0 +1,86 @@
+package clir
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func newDocsCli() *Cli {
+	c := NewCli("app", "An example app", "v1.0.0")
+	var config string
+	c.PersistentFlags().StringFlag("config", "The config file", &config).
+		EnvVar("config", "APP_CONFIG")
+	db := c.NewSubCommand("db", "Database commands")
+	db.LongDescription("Manage the database.\n\n.Lines starting with a dot are escaped.")
+	migrate := db.NewSubCommand("migrate", "Migrate the database")
+	steps := 5
+	var target string
+	migrate.IntFlag("steps", "The number of steps", &steps).
+		ShortFlag("steps", "s").
+		StringFlag("target", "The target version", &target).
+		Required("target")
+	migrate.Action(func() error { return nil })
+	c.NewSubCommand("secret", "Secret commands").Hidden()
+	return c
+}
+
+func TestCommand_WriteManPage(t *testing.T) {
+	c := newDocsCli()
+	migrate := c.rootCommand.subCommandsMap["db"].subCommandsMap["migrate"]
+	var buffer bytes.Buffer
+	if err := migrate.WriteManPage(&buffer); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	page := buffer.String()
+	for _, expected := range []string{
+		`.TH "APP\-DB\-MIGRATE" "1" "" "app v1.0.0" "app Manual"`,
+		`app\-db\-migrate \- Migrate the database`,
+		`\fBapp db migrate\fR \-\-target <string> [flags]`,
+		`\fB\-s\fR, \fB\-\-steps\fR \fIint\fR`,
+		`Default: 5.`,
+		`Required.`,
+		".SH GLOBAL OPTIONS\n.TP\n\\fB\\-\\-config\\fR \\fIstring\\fR\nThe config file\n.br\nEnvironment: APP_CONFIG.",
+		`\fBapp\-db\fR(1)`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("expected man page to contain %q, got:\n%s", expected, page)
+		}
+	}
+
+	buffer.Reset()
+	if err := c.rootCommand.subCommandsMap["db"].WriteManPage(&buffer); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	page = buffer.String()
+	if !strings.Contains(page, "Manage the database.\n.PP\n\\&.Lines starting") {
+		t.Errorf("expected escaped description paragraphs, got:\n%s", page)
+	}
+	if !strings.Contains(page, ".SH COMMANDS\n.TP\n\\fBmigrate\\fR") {
+		t.Errorf("expected subcommand list, got:\n%s", page)
+	}
+}
+
+func TestCli_WriteManPages(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "man")
+	c := newDocsCli().EnableManPages()
+	if err := c.Run("gen-man", dir); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	expected := "app-db-migrate.1,app-db.1,app.1"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("expected pages %s, got %v", expected, names)
+	}
+}
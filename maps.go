@@ -0,0 +1,154 @@
+package clir
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// elementType parses and formats the keys or values of a map flag
+type elementType struct {
+	parse  func(text string) (reflect.Value, error)
+	format func(value reflect.Value) string
+}
+
+// newElementType returns the element type for the given type, which may
+// be of a basic kind or a value type, or nil if it is not supported
+func newElementType(typ reflect.Type) *elementType {
+	if kind := scalarValueType(typ, ""); kind != nil {
+		return &elementType{
+			parse: func(text string) (reflect.Value, error) {
+				value, err := kind.parse(text)
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				return reflect.ValueOf(value), nil
+			},
+			format: func(value reflect.Value) string {
+				return kind.format(value.Interface())
+			},
+		}
+	}
+	if basicTypes[typ.Kind()] == nil {
+		return nil
+	}
+	return &elementType{
+		parse: func(text string) (reflect.Value, error) {
+			return parseBasicValue(text, typ)
+		},
+		format: func(value reflect.Value) string {
+			return fmt.Sprint(value.Interface())
+		},
+	}
+}
+
+// mapValue is a flag.Value for a map, which is given `key=value` pairs.
+// Several pairs may be given at once, separated by the separator.
+type mapValue struct {
+	target    reflect.Value
+	separator string
+	key       *elementType
+	value     *elementType
+}
+
+// newMapValue returns a flag.Value that adds to the given addressable map.
+// It panics if the type of the map's keys or values is not supported.
+func newMapValue(target reflect.Value, separator string) *mapValue {
+	result := &mapValue{
+		target:    target,
+		separator: separator,
+		key:       newElementType(target.Type().Key()),
+		value:     newElementType(target.Type().Elem()),
+	}
+	if result.key == nil || result.value == nil {
+		panic("Unsupported type for map flag: " + target.Type().String())
+	}
+	return result
+}
+
+// Set - Adds the given `key=value` pairs to the map
+func (m *mapValue) Set(text string) error {
+	pairs := []string{text}
+	if m.separator != "" {
+		pairs = strings.Split(text, m.separator)
+	}
+	for _, pair := range pairs {
+		keyText, valueText, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("%q is not of the form key=value", pair)
+		}
+		key, err := m.key.parse(keyText)
+		if err != nil {
+			return err
+		}
+		value, err := m.value.parse(valueText)
+		if err != nil {
+			return err
+		}
+		if m.target.IsNil() {
+			m.target.Set(reflect.MakeMap(m.target.Type()))
+		}
+		m.target.SetMapIndex(key, value)
+	}
+	return nil
+}
+
+// String - Returns the pairs in the map, sorted by key, or blank if it is
+// empty
+func (m *mapValue) String() string {
+	if m == nil || !m.target.IsValid() || m.target.Len() == 0 {
+		return ""
+	}
+	var pairs []string
+	for iter := m.target.MapRange(); iter.Next(); {
+		pairs = append(pairs, m.key.format(iter.Key())+"="+m.value.format(iter.Value()))
+	}
+	sort.Strings(pairs)
+	separator := m.separator
+	if separator == "" {
+		separator = ","
+	}
+	return strings.Join(pairs, separator)
+}
+
+func (m *mapValue) variable() reflect.Value {
+	return m.target
+}
+
+func (m *mapValue) typeName() string {
+	return "key=value"
+}
+
+// addMapField adds a flag for a struct field that is a map. Pairs are
+// separated by commas unless another separator is given.
+func (c *Command) addMapField(name, description, defaultValue, separator, pos string, field reflect.Value) {
+	if separator == "" {
+		separator = ","
+	}
+	value := newMapValue(field, separator)
+	if defaultValue != "" {
+		if err := value.Set(defaultValue); err != nil {
+			panic("Invalid default value for map flag")
+		}
+	}
+	c.flags.Var(value, name, description)
+	c.flagCount++
+	if pos != "" {
+		c.positionalDetails[pos].value = value
+	}
+}
+
+// StringMapFlag - Adds a flag that is given `key=value` pairs, EG:
+// `--label env=prod --label team=core` or `--label env=prod,team=core`
+func (c *Command) StringMapFlag(name, description string, variable *map[string]string) *Command {
+	c.addMapField(name, description, "", "", "", reflect.ValueOf(variable).Elem())
+	return c
+}
+
+// StringMapFlag - Adds a flag to the root command that is given `key=value`
+// pairs.
+func (c *Cli) StringMapFlag(name, description string, variable *map[string]string) *Cli {
+	c.rootCommand.StringMapFlag(name, description, variable)
+	return c
+}
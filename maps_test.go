@@ -0,0 +1,107 @@
+package clir
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type buildFlags struct {
+	Labels    map[string]string        `name:"label" description:"The labels to apply" default:"env=dev"`
+	Limits    map[string]int           `name:"limit" description:"The resource limits"`
+	Args      map[string]string        `name:"build-arg" description:"The build arguments" sep:";"`
+	Timeouts  map[string]time.Duration `name:"timeout" description:"The timeouts by stage"`
+	Overrides map[int]bool             `name:"override" description:"The overrides by step"`
+}
+
+func TestCommand_MapFlags(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	flags := &buildFlags{}
+	c.AddFlags(flags).Action(func() error { return nil })
+	if !reflect.DeepEqual(flags.Labels, map[string]string{"env": "dev"}) {
+		t.Errorf("unexpected default labels: %v", flags.Labels)
+	}
+
+	e := c.Run("--label", "env=prod", "--label", "team=core,tier=web", "--limit", "cpu=2", "--build-arg", "A=1,2;B=3",
+		"--timeout", "test=5m", "--override", "3=true")
+	if e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if !reflect.DeepEqual(flags.Labels, map[string]string{"env": "prod", "team": "core", "tier": "web"}) {
+		t.Errorf("unexpected labels: %v", flags.Labels)
+	}
+	if !reflect.DeepEqual(flags.Limits, map[string]int{"cpu": 2}) || !reflect.DeepEqual(flags.Args, map[string]string{"A": "1,2", "B": "3"}) {
+		t.Errorf("unexpected values: %v, %v", flags.Limits, flags.Args)
+	}
+	if flags.Timeouts["test"] != 5*time.Minute || !flags.Overrides[3] {
+		t.Errorf("unexpected values: %v, %v", flags.Timeouts, flags.Overrides)
+	}
+
+	// The defaults are restored without the values added since
+	c.ResetFlags()
+	if !reflect.DeepEqual(flags.Labels, map[string]string{"env": "dev"}) || flags.Limits != nil {
+		t.Errorf("expected maps to be reset, got %v, %v", flags.Labels, flags.Limits)
+	}
+
+	os.Setenv("APP_LIMITS", "cpu=1,memory=512")
+	defer os.Unsetenv("APP_LIMITS")
+	c.EnvVar("limit", "APP_LIMITS")
+	if e := c.Run("--label", "env=test"); e != nil || !reflect.DeepEqual(flags.Limits, map[string]int{"cpu": 1, "memory": 512}) {
+		t.Errorf("expected limits from the environment, got %v, %v", e, flags.Limits)
+	}
+
+	tests := map[string][]string{
+		`invalid value "prod" for flag --label: "prod" is not of the form key=value`: {"--label", "prod"},
+		`invalid value "cpu=lots" for flag --limit: parse error`:                     {"--limit", "cpu=lots"},
+	}
+	for message, args := range tests {
+		c.ResetFlags()
+		e := c.Run(args...)
+		var invalidValue *InvalidValueError
+		if !errors.As(e, &invalidValue) || invalidValue.Error() != message {
+			t.Errorf("%v: expected %q, got %v", args, message, e)
+		}
+	}
+}
+
+func TestCommand_StringMapFlag(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	labels := map[string]string{"team": "core"}
+	c.StringMapFlag("label", "The labels to apply", &labels).Action(func() error { return nil })
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if e := c.Run("--help"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	expected := "  --label key=value\n    \tThe labels to apply (default team=core)\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected help to contain %q, got:\n%s", expected, output.String())
+	}
+
+	if e := c.Run("--label", "env=prod"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	// Values given on the command line replace the default
+	if !reflect.DeepEqual(labels, map[string]string{"env": "prod"}) {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+	if values := c.rootCommand.FlagValues(); values["label"] != "env=prod" {
+		t.Errorf("expected the current map, got %q", values["label"])
+	}
+}
+
+func TestCommand_MapFlagUnsupported(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "Unsupported type for map flag") {
+			t.Errorf("expected panic for unsupported map type, got %v", r)
+		}
+	}()
+	NewCli("app", "description", "0").AddFlags(&struct {
+		Values map[string][]string `name:"values"`
+	}{})
+}
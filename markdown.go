@@ -0,0 +1,130 @@
+package clir
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteMarkdownDocs - Writes a Markdown page for each command that isn't
+// hidden to the given directory, along with an `index.md` page listing them.
+// Pages are named after the command path, EG: `app-db-migrate.md`
+func (c *Cli) WriteMarkdownDocs(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var index strings.Builder
+	fmt.Fprintf(&index, "# %s\n\n", c.Name())
+	if c.ShortDescription() != "" {
+		index.WriteString(c.ShortDescription() + "\n\n")
+	}
+	index.WriteString("## Commands\n\n| Command | Description |\n|---------|-------------|\n")
+
+	err := c.rootCommand.walkVisible(func(command *Command) error {
+		fmt.Fprintf(&index, "| [%s](%s.md) | %s |\n", command.commandPath, command.pageName(), markdownCell(command.shortdescription))
+		return writeFile(filepath.Join(dir, command.pageName()+".md"), command.WriteMarkdown)
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "index.md"), []byte(index.String()), 0644)
+}
+
+// WriteMarkdown - Writes the Markdown page for the application to the given
+// writer
+func (c *Cli) WriteMarkdown(w io.Writer) error {
+	return c.rootCommand.WriteMarkdown(w)
+}
+
+// WriteMarkdown - Writes the Markdown reference page for the command to the
+// given writer. Links to other commands assume the pages were written by
+// Cli.WriteMarkdownDocs.
+func (c *Command) WriteMarkdown(w io.Writer) error {
+	c.mergePersistentFlags()
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", c.commandPath)
+	if c.shortdescription != "" {
+		b.WriteString(c.shortdescription + "\n\n")
+	}
+	if len(c.aliases) > 0 {
+		b.WriteString("Aliases: `" + strings.Join(c.aliases, "`, `") + "`\n\n")
+	}
+	fmt.Fprintf(&b, "## Usage\n\n```\n%s\n```\n\n", c.usage())
+	if c.longdescription != "" {
+		fmt.Fprintf(&b, "## Description\n\n%s\n\n", strings.TrimSpace(c.longdescription))
+	}
+
+	local, global := c.helpFlags()
+	if len(local) > 0 {
+		b.WriteString("## Flags\n\n")
+		writeMarkdownFlags(&b, local)
+	}
+	if len(global) > 0 {
+		b.WriteString("## Global Flags\n\n")
+		writeMarkdownFlags(&b, global)
+	}
+	if groups := c.helpFlagGroups(); len(groups) > 0 {
+		b.WriteString("## Flag Groups\n\n")
+		for _, group := range groups {
+			b.WriteString("* " + group.Description() + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	subcommands := c.visibleSubCommands()
+	if len(subcommands) > 0 {
+		b.WriteString("## Commands\n\n| Command | Description |\n|---------|-------------|\n")
+		for _, subcommand := range subcommands {
+			name := fmt.Sprintf("[%s](%s.md)", subcommand.name, subcommand.pageName())
+			if len(subcommand.aliases) > 0 {
+				name += " (`" + strings.Join(subcommand.aliases, "`, `") + "`)"
+			}
+			fmt.Fprintf(&b, "| %s | %s |\n", name, markdownCell(subcommand.shortdescription))
+		}
+		b.WriteString("\n")
+	}
+
+	if c.parent != nil {
+		fmt.Fprintf(&b, "## See Also\n\n* [%s](%s.md) - %s\n\n", c.parent.commandPath, c.parent.pageName(), c.parent.shortdescription)
+	}
+
+	_, err := io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
+
+// writeMarkdownFlags writes the given flags as a Markdown table
+func writeMarkdownFlags(b *strings.Builder, flags []*HelpFlag) {
+	b.WriteString("| Flag | Type | Default | Environment | Description |\n")
+	b.WriteString("|------|------|---------|-------------|-------------|\n")
+	for _, f := range flags {
+		name := "`--" + f.Name + "`"
+		if f.Short != "" {
+			name = "`-" + f.Short + "`, " + name
+		}
+		defaultValue := ""
+		if f.Default != "" {
+			defaultValue = "`" + f.Default + "`"
+		}
+		env := ""
+		if f.Env != "" {
+			env = "`" + f.Env + "`"
+		}
+		description := f.Usage
+		if len(f.Choices) > 0 {
+			description += " (one of: `" + strings.Join(f.Choices, "`, `") + "`)"
+		}
+		if f.Required {
+			description += " (required)"
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s |\n", name, f.Type, markdownCell(defaultValue), env, markdownCell(description))
+	}
+	b.WriteString("\n")
+}
+
+// markdownCell escapes the given text for use in a Markdown table cell
+func markdownCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\n", "<br>").Replace(text)
+}
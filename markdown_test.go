@@ -0,0 +1,63 @@
+package clir
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommand_WriteMarkdown(t *testing.T) {
+	c := newDocsCli()
+	migrate := c.rootCommand.subCommandsMap["db"].subCommandsMap["migrate"]
+	var buffer bytes.Buffer
+	if err := migrate.WriteMarkdown(&buffer); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	page := buffer.String()
+	for _, expected := range []string{
+		"# app db migrate\n\nMigrate the database\n",
+		"```\napp db migrate --target <string> [flags]\n```",
+		"| `-s`, `--steps` | int | `5` |  | The number of steps |",
+		"| `--target` | string |  |  | The target version (required) |",
+		"## Global Flags\n\n",
+		"| `--config` | string |  | `APP_CONFIG` | The config file |",
+		"* [app db](app-db.md) - Database commands",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("expected page to contain %q, got:\n%s", expected, page)
+		}
+	}
+
+	buffer.Reset()
+	if err := c.WriteMarkdown(&buffer); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	page = buffer.String()
+	if !strings.Contains(page, "| [db](app-db.md) | Database commands |") {
+		t.Errorf("expected subcommand links, got:\n%s", page)
+	}
+	if strings.Contains(page, "secret") {
+		t.Errorf("expected hidden commands to be omitted, got:\n%s", page)
+	}
+}
+
+func TestCli_WriteMarkdownDocs(t *testing.T) {
+	dir := t.TempDir()
+	if err := newDocsCli().WriteMarkdownDocs(dir); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, name := range []string{"app.md", "app-db.md", "app-db-migrate.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected page %s to be written: %v", name, err)
+		}
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(index), "| [app db migrate](app-db-migrate.md) | Migrate the database |") {
+		t.Errorf("expected index to link to every command, got:\n%s", index)
+	}
+}
@@ -0,0 +1,104 @@
+package clir
+
+import (
+	"flag"
+	"reflect"
+)
+
+// boundValue is implemented by flag values that hold a reference to the
+// variable they set rather than being the variable themselves
+type boundValue interface {
+	variable() reflect.Value
+}
+
+// savedValue is the initial value of a variable bound to a flag or
+// positional argument
+type savedValue struct {
+	target reflect.Value
+	value  reflect.Value
+}
+
+// ResetFlags - Restores every flag and positional argument to the value it
+// had before the application first ran. This allows the same application
+// to be run multiple times, EG: in tests, without values leaking from one
+// run to the next.
+func (c *Cli) ResetFlags() *Cli {
+	c.saveFlags()
+	for _, saved := range c.savedValues {
+		saved.target.Set(copyValue(saved.value))
+	}
+	c.executed = nil
+	return c
+}
+
+// saveFlags records the value of any flag or positional argument that has
+// not yet been saved. It is called before each run, so values are saved
+// before they are first changed.
+func (c *Cli) saveFlags() {
+	if c.savedValues == nil {
+		c.savedValues = make(map[interface{}]*savedValue)
+	}
+	c.rootCommand.walk(func(command *Command) {
+		for _, flags := range []*Command{command, command.persistentFlags} {
+			if flags == nil || flags.flags == nil {
+				continue
+			}
+			flags.flags.VisitAll(func(f *flag.Flag) {
+				if bound, ok := underlyingValue(f).(boundValue); ok {
+					c.saveValue(bound.variable())
+					return
+				}
+				value := reflect.ValueOf(underlyingValue(f))
+				if value.Kind() == reflect.Ptr && !value.IsNil() {
+					c.saveValue(value.Elem())
+				}
+			})
+		}
+		for _, field := range command.positionalArgsMap {
+			c.saveValue(field)
+		}
+	})
+}
+
+// saveValue records the value of the given variable if it has not
+// already been saved
+func (c *Cli) saveValue(target reflect.Value) {
+	if !target.CanAddr() || !target.CanSet() {
+		return
+	}
+	key := target.Addr().Interface()
+	if _, exists := c.savedValues[key]; exists {
+		return
+	}
+	c.savedValues[key] = &savedValue{
+		target: target,
+		value:  copyValue(target),
+	}
+}
+
+// copyValue returns a copy of the given value. Slices and maps are copied
+// so that values added to the original are not seen by the copy.
+func copyValue(value reflect.Value) reflect.Value {
+	result := reflect.New(value.Type()).Elem()
+	switch {
+	case value.Kind() == reflect.Slice && !value.IsNil():
+		result.Set(reflect.AppendSlice(reflect.MakeSlice(value.Type(), 0, value.Len()), value))
+	case value.Kind() == reflect.Map && !value.IsNil():
+		result.Set(reflect.MakeMapWithSize(value.Type(), value.Len()))
+		for iter := value.MapRange(); iter.Next(); {
+			result.SetMapIndex(iter.Key(), iter.Value())
+		}
+	case value.Kind() != reflect.Slice && value.Kind() != reflect.Map:
+		result.Set(value)
+	}
+	return result
+}
+
+// walk calls fn for this command and all of its descendants, including
+// those that are hidden
+func (c *Command) walk(fn func(*Command)) {
+	fn(c)
+	for _, subcommand := range c.subCommands {
+		subcommand.walk(fn)
+	}
+}
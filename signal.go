@@ -0,0 +1,45 @@
+package clir
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exit is used to terminate the application. It is a variable so that it
+// may be replaced in tests.
+var exit = os.Exit
+
+// notifyContext returns a copy of the given context that is cancelled when
+// SIGINT or SIGTERM is received. A second signal terminates the application
+// with the conventional exit code of 128 plus the signal number.
+// The returned function stops listening for signals.
+func notifyContext(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-done:
+			return
+		}
+		select {
+		case sig := <-signals:
+			code := 1
+			if number, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(number)
+			}
+			exit(code)
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
@@ -0,0 +1,62 @@
+package clir
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNotifyContext(t *testing.T) {
+	exitCode := make(chan int, 1)
+	exit = func(code int) { exitCode <- code }
+	defer func() { exit = os.Exit }()
+
+	ctx, stop := notifyContext(context.Background())
+	defer stop()
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		t.Skipf("unable to send signal: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected context to be cancelled")
+	}
+
+	// A second signal exits the application
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case code := <-exitCode:
+		if code != 128+int(syscall.SIGTERM) {
+			t.Errorf("expected exit code %d, got %d", 128+int(syscall.SIGTERM), code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected application to exit")
+	}
+}
+
+func TestCli_HandleSignals(t *testing.T) {
+	c := NewCli("test", "description", "0").HandleSignals()
+	started := make(chan struct{})
+	c.ActionContext(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	go func() {
+		<-started
+		process, _ := os.FindProcess(os.Getpid())
+		_ = process.Signal(os.Interrupt)
+	}()
+	if err := c.Run("--help=false"); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
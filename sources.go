@@ -0,0 +1,131 @@
+package clir
+
+import (
+	"flag"
+	"reflect"
+)
+
+// flagSource is where a flag's value was set from, in increasing order of
+// precedence
+type flagSource int
+
+const (
+	sourceDefault flagSource = iota
+	sourceConfig
+	sourceEnv
+	sourceCommandLine
+)
+
+// accumulatingValue is implemented by flag values that add to their
+// variable each time they are set, such as slices and maps
+type accumulatingValue interface {
+	flag.Value
+	clear()
+}
+
+func (f *sliceValue[T]) clear() { *f = nil }
+
+func (v *namedSliceValue) clear() { v.target.Set(reflect.Zero(v.target.Type())) }
+
+func (v *typedValue) clear() {
+	if v.slice {
+		v.target.Set(reflect.Zero(v.target.Type()))
+	}
+}
+
+func (m *mapValue) clear() { m.target.Set(reflect.Zero(m.target.Type())) }
+
+// customValue is a flag.Value of the application's own that is a slice or
+// map, such as a struct field implementing flag.Value, which may add to
+// itself each time it is set
+type customValue struct {
+	flag.Value
+}
+
+func (v customValue) clear() {
+	target := reflect.ValueOf(v.Value).Elem()
+	target.Set(reflect.Zero(target.Type()))
+}
+
+// asAccumulating returns the given flag value as an accumulating value, if
+// it is one
+func asAccumulating(value flag.Value) (accumulatingValue, bool) {
+	if choice, ok := value.(*choiceValue); ok {
+		value = choice.Value
+	}
+	if accumulating, ok := value.(accumulatingValue); ok {
+		return accumulating, true
+	}
+	pointer := reflect.ValueOf(value)
+	if pointer.Kind() != reflect.Ptr || pointer.IsNil() {
+		return nil, false
+	}
+	switch pointer.Elem().Kind() {
+	case reflect.Slice, reflect.Map:
+		return customValue{value}, true
+	}
+	return nil, false
+}
+
+// useSource records that the value is being set from the given source.
+// Accumulating values are cleared when first set from a source that takes
+// precedence over the one that set them, so that its values replace those
+// of the default, config file or environment rather than adding to them.
+func (c *Command) useSource(value flag.Value, source flagSource) {
+	accumulating, ok := asAccumulating(value)
+	if !ok || c.app == nil {
+		return
+	}
+	if c.app.flagSources == nil {
+		c.app.flagSources = make(map[accumulatingValue]flagSource)
+	}
+	if c.app.flagSources[accumulating] < source {
+		accumulating.clear()
+		c.app.flagSources[accumulating] = source
+	}
+}
+
+// setFlag sets the named flag from the given source
+func (c *Command) setFlag(name, value string, source flagSource) error {
+	if f := c.flags.Lookup(name); f != nil {
+		c.useSource(f.Value, source)
+	}
+	return c.flags.Set(name, value)
+}
+
+// commandLineValue wraps a flag's value while the command line is parsed,
+// so that accumulating values replace those set from other sources
+type commandLineValue struct {
+	flag.Value
+	command *Command
+}
+
+// Set - Sets the wrapped value from the command line
+func (v *commandLineValue) Set(text string) error {
+	v.command.useSource(v.Value, sourceCommandLine)
+	return v.Value.Set(text)
+}
+
+// IsBoolFlag - Returns true if the wrapped value does not require a value
+func (v *commandLineValue) IsBoolFlag() bool {
+	boolFlag, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// parseCommandLine parses the given command line arguments using the
+// command's flag set
+func (c *Command) parseCommandLine(args []string) error {
+	var wrapped []*flag.Flag
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if _, ok := asAccumulating(f.Value); ok {
+			f.Value = &commandLineValue{Value: f.Value, command: c}
+			wrapped = append(wrapped, f)
+		}
+	})
+	defer func() {
+		for _, f := range wrapped {
+			f.Value = f.Value.(*commandLineValue).Value
+		}
+	}()
+	return c.flags.Parse(args)
+}
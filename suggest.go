@@ -0,0 +1,89 @@
+package clir
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestionDistance is the largest edit distance at which a candidate
+// is suggested for a mistyped name
+const maxSuggestionDistance = 2
+
+// suggestions returns the candidates that are similar to the given name:
+// those it is a prefix of, or that are within a small edit distance of it.
+// The closest candidates are returned first.
+func suggestions(name string, candidates []string) []string {
+	name = strings.ToLower(name)
+	// Short names are close to everything, so require fewer edits
+	maxDistance := len(name) / 2
+	if maxDistance > maxSuggestionDistance {
+		maxDistance = maxSuggestionDistance
+	}
+	distances := make(map[string]int)
+	var result []string
+	for _, candidate := range candidates {
+		lower := strings.ToLower(candidate)
+		distance := levenshtein(name, lower)
+		if distance > maxDistance && !(name != "" && strings.HasPrefix(lower, name)) {
+			continue
+		}
+		if _, exists := distances[candidate]; !exists {
+			result = append(result, candidate)
+		}
+		distances[candidate] = distance
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if distances[result[i]] != distances[result[j]] {
+			return distances[result[i]] < distances[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// levenshtein returns the number of single character insertions,
+// deletions and substitutions required to turn a into b
+func levenshtein(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(target)]
+}
+
+// min3 returns the smallest of the given numbers
+func min3(a, b, c int) int {
+	result := a
+	if b < result {
+		result = b
+	}
+	if c < result {
+		result = c
+	}
+	return result
+}
+
+// didYouMean returns the suffix suggesting the given names to the user,
+// EG: `, did you mean 'deploy'?`
+func didYouMean(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return ", did you mean '" + names[0] + "'?"
+	}
+	return ", did you mean one of '" + strings.Join(names, "', '") + "'?"
+}
@@ -0,0 +1,74 @@
+package clir
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"deploy", "deploy", 0},
+		{"depoly", "deploy", 2},
+		{"deplyo", "deploy", 2},
+		{"stauts", "status", 2},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if distance := levenshtein(test.a, test.b); distance != test.expected {
+			t.Errorf("levenshtein(%q, %q): expected %d, got %d", test.a, test.b, test.expected, distance)
+		}
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	candidates := []string{"deploy", "delete", "describe", "status"}
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"depoly", []string{"deploy"}},
+		{"de", []string{"delete", "deploy", "describe"}},
+		{"STATUS", []string{"status"}},
+		{"x", nil},
+		{"unrelated", nil},
+	}
+	for _, test := range tests {
+		if result := suggestions(test.name, candidates); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("suggestions(%q): expected %v, got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestCli_Suggestions(t *testing.T) {
+	c := NewCli("app", "An example app", "v1.0.0")
+	deploy := c.NewSubCommand("deploy", "Deploy the app")
+	var region string
+	deploy.StringFlag("region", "The region", &region)
+	deploy.Action(func() error { return nil })
+	c.NewSubCommand("depot", "A hidden command").Hidden()
+
+	err := c.Run("depoly", "--region", "eu")
+	var unknownCommand *UnknownCommandError
+	if !errors.As(err, &unknownCommand) || !reflect.DeepEqual(unknownCommand.Suggestions, []string{"deploy"}) {
+		t.Fatalf("expected deploy to be suggested, got %#v", err)
+	}
+	expected := "Error: unknown command 'depoly' for 'app', did you mean 'deploy'?\nSee 'app --help' for usage"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	err = c.Run("deploy", "--regoin", "eu")
+	var unknownFlag *UnknownFlagError
+	if !errors.As(err, &unknownFlag) || !reflect.DeepEqual(unknownFlag.Suggestions, []string{"region"}) {
+		t.Fatalf("expected region to be suggested, got %#v", err)
+	}
+	if unknownFlag.Error() != "flag provided but not defined: --regoin, did you mean '--region'?" {
+		t.Errorf("unexpected message: %q", unknownFlag.Error())
+	}
+}
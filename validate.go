@@ -0,0 +1,289 @@
+package clir
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fieldRule is a validation rule given by a struct tag. It returns the
+// offending value and an error if the value is invalid.
+type fieldRule struct {
+	check func(value reflect.Value) (string, error)
+	// always is true if the rule is checked even when the field has its
+	// zero value and was not given
+	always bool
+}
+
+// fieldValidation holds the validation rules of a struct field bound to a
+// flag or positional argument
+type fieldValidation struct {
+	name  string
+	pos   string
+	field reflect.Value
+	rules []*fieldRule
+}
+
+// validator is implemented by flag structs that validate themselves
+type validator interface {
+	Validate() error
+}
+
+// addValidation reads the validation tags of a struct field, panicking if
+// they are invalid
+func (c *Command) addValidation(name, pos string, field reflect.Value, fieldType reflect.StructField) {
+	var rules []*fieldRule
+	for _, tagName := range []string{"min", "max", "len", "regex", "oneof", "nonempty", "file-exists", "dir-exists", "url"} {
+		tag, ok := fieldType.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+		rule, err := newFieldRule(tagName, tag, field.Type())
+		if err != nil {
+			panic("Invalid " + tagName + " tag on field " + fieldType.Name + ": " + err.Error())
+		}
+		if rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) > 0 {
+		c.validations = append(c.validations, &fieldValidation{name: name, pos: pos, field: field, rules: rules})
+	}
+}
+
+// newFieldRule returns the rule for the given tag and value. It returns
+// nil if the tag disables the rule, EG: `nonempty:"false"`.
+func newFieldRule(tagName, tag string, typ reflect.Type) (*fieldRule, error) {
+	switch tagName {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(tag, 64)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := measure(reflect.Zero(typ)); !ok {
+			return nil, errors.New("unsupported type " + typ.String())
+		}
+		return &fieldRule{check: func(value reflect.Value) (string, error) {
+			size, _ := measure(value)
+			if tagName == "min" && size < limit || tagName == "max" && size > limit {
+				bound := "at least"
+				if tagName == "max" {
+					bound = "at most"
+				}
+				return formatValue(value), fmt.Errorf("must be %s %s%s", bound, tag, measureUnit(typ))
+			}
+			return "", nil
+		}}, nil
+	case "len":
+		length, err := strconv.Atoi(tag)
+		if err != nil {
+			return nil, err
+		}
+		if typ.Kind() != reflect.String && typ.Kind() != reflect.Slice {
+			return nil, errors.New("unsupported type " + typ.String())
+		}
+		return &fieldRule{check: func(value reflect.Value) (string, error) {
+			if size, _ := measure(value); int(size) != length {
+				return formatValue(value), fmt.Errorf("must be exactly %s%s", tag, measureUnit(typ))
+			}
+			return "", nil
+		}}, nil
+	case "regex":
+		pattern, err := regexp.Compile(tag)
+		if err != nil {
+			return nil, err
+		}
+		return eachRule(func(value string) error {
+			if !pattern.MatchString(value) {
+				return fmt.Errorf("must match %s", tag)
+			}
+			return nil
+		}), nil
+	case "oneof":
+		choices := strings.Split(tag, ",")
+		return eachRule(func(value string) error {
+			if !isChoice(value, choices) {
+				return choiceError(choices)
+			}
+			return nil
+		}), nil
+	case "nonempty":
+		enabled, err := strconv.ParseBool(tag)
+		if err != nil || !enabled {
+			return nil, err
+		}
+		return &fieldRule{always: true, check: func(value reflect.Value) (string, error) {
+			if value.IsZero() || value.Kind() == reflect.Slice && value.Len() == 0 {
+				return "", errors.New("must not be empty")
+			}
+			return "", nil
+		}}, nil
+	case "file-exists", "dir-exists":
+		enabled, err := strconv.ParseBool(tag)
+		if err != nil || !enabled {
+			return nil, err
+		}
+		wantDir := tagName == "dir-exists"
+		return eachRule(func(value string) error {
+			info, err := os.Stat(value)
+			switch {
+			case err != nil && wantDir:
+				return errors.New("directory does not exist")
+			case err != nil:
+				return errors.New("file does not exist")
+			case wantDir && !info.IsDir():
+				return errors.New("is not a directory")
+			case !wantDir && info.IsDir():
+				return errors.New("is a directory, not a file")
+			}
+			return nil
+		}), nil
+	case "url":
+		enabled, err := strconv.ParseBool(tag)
+		if err != nil || !enabled {
+			return nil, err
+		}
+		return eachRule(func(value string) error {
+			parsed, err := url.Parse(value)
+			if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				return errors.New("must be a URL")
+			}
+			return nil
+		}), nil
+	}
+	return nil, nil
+}
+
+// eachRule returns a rule that checks the value, or each value of a slice,
+// using the given function. Empty values are not checked.
+func eachRule(check func(value string) error) *fieldRule {
+	return &fieldRule{check: func(value reflect.Value) (string, error) {
+		values := []reflect.Value{value}
+		if value.Kind() == reflect.Slice {
+			values = values[:0]
+			for index := 0; index < value.Len(); index++ {
+				values = append(values, value.Index(index))
+			}
+		}
+		for _, item := range values {
+			text := formatValue(item)
+			if text == "" {
+				continue
+			}
+			if err := check(text); err != nil {
+				return text, err
+			}
+		}
+		return "", nil
+	}}
+}
+
+// measure returns the size of the value used by the min and max rules: the
+// number itself, the length of a string or the number of values in a slice
+func measure(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.String:
+		return float64(len([]rune(value.String()))), true
+	case reflect.Slice:
+		return float64(value.Len()), true
+	}
+	return 0, false
+}
+
+// measureUnit returns the unit of the size returned by measure for the
+// given type, EG: ` characters`
+func measureUnit(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice:
+		return " values"
+	}
+	return ""
+}
+
+// formatValue returns the value as it would be given on the command line
+func formatValue(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return value.String()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// validate checks the validation rules of the command's flags and
+// positional arguments, returning all the failures together. If they pass,
+// the Validate method of any flag struct that implements it is called.
+func (c *Command) validate() error {
+	var failures ValidationErrors
+	for _, validation := range c.validations {
+		given := c.isFlagSet(validation.name)
+		if index, err := strconv.Atoi(validation.pos); err == nil {
+			given = given || c.flags.NArg() >= index
+		}
+		unset := !given && validation.field.IsZero()
+		for _, rule := range validation.rules {
+			if unset && !rule.always {
+				continue
+			}
+			value, err := rule.check(validation.field)
+			if err == nil {
+				continue
+			}
+			failure := &ValidationError{Command: c.commandPath, Value: value, Err: err}
+			if validation.pos != "" {
+				failure.Arg = validation.name
+			} else {
+				failure.Flag = validation.name
+			}
+			failures = append(failures, failure)
+			break
+		}
+	}
+	if len(failures) == 1 {
+		return failures[0]
+	}
+	if len(failures) > 0 {
+		return failures
+	}
+	for _, flags := range c.flagStructs {
+		if v, ok := flags.Interface().(validator); ok {
+			if err := v.Validate(); err != nil {
+				return c.validationError(err)
+			}
+		}
+	}
+	return nil
+}
+
+// validationError returns the error returned by a Validate method as a
+// validation error for the command
+func (c *Command) validationError(err error) error {
+	var validation *ValidationError
+	var failures ValidationErrors
+	switch {
+	case errors.As(err, &failures):
+		for _, failure := range failures {
+			if failure.Command == "" {
+				failure.Command = c.commandPath
+			}
+		}
+		return err
+	case errors.As(err, &validation):
+		if validation.Command == "" {
+			validation.Command = c.commandPath
+		}
+		return err
+	}
+	return &ValidationError{Command: c.commandPath, Err: err}
+}
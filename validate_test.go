@@ -0,0 +1,135 @@
+package clir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type deployFlags struct {
+	Replicas int      `name:"replicas" min:"1" max:"10" default:"1"`
+	Name     string   `name:"name" nonempty:"true" len:"6" regex:"^[a-z]+$"`
+	Tags     []string `name:"tag" max:"2" oneof:"blue,green"`
+	Config   string   `name:"config" file-exists:"true"`
+	Dir      string   `name:"dir" dir-exists:"true"`
+	Endpoint string   `name:"endpoint" url:"true"`
+	Target   string   `pos:"1" name:"target" regex:"^(dev|prod)$"`
+}
+
+type credentials struct {
+	User     string `name:"user"`
+	Password string `name:"password"`
+}
+
+func (c *credentials) Validate() error {
+	if c.User != "" && c.Password == "" {
+		return errors.New("a password is required with a user")
+	}
+	return nil
+}
+
+func TestCommand_ValidationTags(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(config, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCli("app", "description", "0")
+	c.NewSubCommandFunction("deploy", "Deploy the app", func(flags *deployFlags) error {
+		return nil
+	})
+
+	valid := []string{"deploy", "--name", "widget", "--replicas", "3", "--tag", "blue", "--config", config, "--dir", dir, "--endpoint", "https://example.com", "dev"}
+	if e := c.Run(valid...); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+
+	tests := []struct {
+		args    []string
+		message string
+	}{
+		{[]string{"--replicas", "0"}, "validation failed for flag --replicas: must be at least 1"},
+		{[]string{"--replicas", "11"}, "validation failed for flag --replicas: must be at most 10"},
+		{[]string{"--name", "gadgets"}, "validation failed for flag --name: must be exactly 6 characters"},
+		{[]string{"--name", "Widget"}, "validation failed for flag --name: must match ^[a-z]+$"},
+		{[]string{"--tag", "red"}, "validation failed for flag --tag: must be one of: blue, green"},
+		{[]string{"--tag", "blue", "--tag", "green", "--tag", "blue"}, "validation failed for flag --tag: must be at most 2 values"},
+		{[]string{"--config", filepath.Join(dir, "missing.json")}, "validation failed for flag --config: file does not exist"},
+		{[]string{"--config", dir}, "validation failed for flag --config: is a directory, not a file"},
+		{[]string{"--dir", config}, "validation failed for flag --dir: is not a directory"},
+		{[]string{"--endpoint", "example.com"}, "validation failed for flag --endpoint: must be a URL"},
+		{[]string{"staging"}, "validation failed for argument <target>: must match ^(dev|prod)$"},
+	}
+	for _, test := range tests {
+		// Later flags take precedence over those in the valid command line
+		args := append(append([]string{}, valid[:len(valid)-1]...), test.args...)
+		if test.args[0] != "staging" {
+			args = append(args, "dev")
+		}
+		c.ResetFlags()
+		e := c.Run(args...)
+		var validation *ValidationError
+		if !errors.As(e, &validation) || validation.Error() != test.message {
+			t.Errorf("%v: expected %q, got %v", test.args, test.message, e)
+		}
+		if ExitCode(e) != 2 {
+			t.Errorf("%v: expected exit code 2, got %d", test.args, ExitCode(e))
+		}
+	}
+
+	// Every failure is reported, and rules other than nonempty are not
+	// checked for values that were not given
+	c.ResetFlags()
+	e := c.Run("deploy", "--replicas", "0")
+	var failures ValidationErrors
+	if !errors.As(e, &failures) || len(failures) != 2 {
+		t.Fatalf("expected two validation errors, got %v", e)
+	}
+	expected := "validation failed:\n  flag --replicas: must be at least 1\n  flag --name: must not be empty"
+	if failures.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, failures.Error())
+	}
+	var validation *ValidationError
+	if !failures.As(&validation) || validation != failures[0] {
+		t.Errorf("expected the first validation error, got %v", validation)
+	}
+	if ExitCode(e) != 2 {
+		t.Errorf("expected exit code 2, got %d", ExitCode(e))
+	}
+}
+
+func TestCommand_ValidateMethod(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	called := false
+	c.NewSubCommandFunction("login", "Log in", func(flags *credentials) error {
+		called = true
+		return nil
+	})
+
+	e := c.Run("login", "--user", "bob")
+	var validation *ValidationError
+	if !errors.As(e, &validation) || validation.Command != "app login" || !strings.Contains(e.Error(), "a password is required with a user") {
+		t.Errorf("expected a validation error, got %v", e)
+	}
+	if called {
+		t.Errorf("expected the function not to be called when validation fails")
+	}
+	if e := c.Run("login", "--user", "bob", "--password", "secret"); e != nil || !called {
+		t.Errorf("expected the function to be called, got %v", e)
+	}
+}
+
+func TestCommand_ValidationTagInvalid(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "Invalid min tag on field Count") {
+			t.Errorf("expected panic for invalid tag, got %v", r)
+		}
+	}()
+	flags := &struct {
+		Count int `min:"one"`
+	}{}
+	NewCli("app", "description", "0").AddFlags(flags)
+}
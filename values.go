@@ -0,0 +1,329 @@
+package clir
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// valueType parses and formats the values of a type that is not one of
+// the basic kinds supported by AddFlags
+type valueType struct {
+	// name is the name of the value shown in help, EG: `duration`
+	name   string
+	parse  func(text string) (interface{}, error)
+	format func(value interface{}) string
+	// direct is true if the type implements flag.Value through a pointer,
+	// so that variables of the type may be used as flag values directly
+	direct bool
+}
+
+// valueTypes are the types supported by AddFlags in addition to the basic
+// kinds. time.Time is handled by timeValueType as it depends on the layout.
+var valueTypes = map[reflect.Type]*valueType{
+	reflect.TypeOf(time.Duration(0)): {
+		name: "duration",
+		parse: func(text string) (interface{}, error) {
+			return time.ParseDuration(text)
+		},
+		format: func(value interface{}) string {
+			return value.(time.Duration).String()
+		},
+	},
+	reflect.TypeOf((*url.URL)(nil)): {
+		name: "url",
+		parse: func(text string) (interface{}, error) {
+			return url.Parse(text)
+		},
+		format: func(value interface{}) string {
+			return value.(*url.URL).String()
+		},
+	},
+	reflect.TypeOf(net.IP(nil)): {
+		name: "ip",
+		parse: func(text string) (interface{}, error) {
+			ip := net.ParseIP(text)
+			if ip == nil {
+				return nil, errors.New("invalid IP address")
+			}
+			return ip, nil
+		},
+		format: func(value interface{}) string {
+			return value.(net.IP).String()
+		},
+	},
+	reflect.TypeOf(net.IPNet{}): {
+		name: "cidr",
+		parse: func(text string) (interface{}, error) {
+			_, network, err := net.ParseCIDR(text)
+			if err != nil {
+				return nil, errors.New("invalid CIDR address")
+			}
+			return *network, nil
+		},
+		format: func(value interface{}) string {
+			network := value.(net.IPNet)
+			return network.String()
+		},
+	},
+	reflect.TypeOf((*regexp.Regexp)(nil)): {
+		name: "regexp",
+		parse: func(text string) (interface{}, error) {
+			return regexp.Compile(text)
+		},
+		format: func(value interface{}) string {
+			return value.(*regexp.Regexp).String()
+		},
+	},
+	reflect.TypeOf((*big.Int)(nil)): {
+		name: "int",
+		parse: func(text string) (interface{}, error) {
+			result, ok := new(big.Int).SetString(text, 0)
+			if !ok {
+				return nil, errors.New("invalid integer")
+			}
+			return result, nil
+		},
+		format: func(value interface{}) string {
+			return value.(*big.Int).String()
+		},
+	},
+	reflect.TypeOf(os.FileMode(0)): {
+		name: "mode",
+		parse: func(text string) (interface{}, error) {
+			mode, err := strconv.ParseUint(text, 8, 32)
+			if err != nil {
+				return nil, errors.New("invalid file mode")
+			}
+			return os.FileMode(mode), nil
+		},
+		format: func(value interface{}) string {
+			return fmt.Sprintf("%#o", uint32(value.(os.FileMode)))
+		},
+	},
+}
+
+// valueTypesLock guards valueTypes, which RegisterType adds to
+var valueTypesLock sync.RWMutex
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// timeValueType returns the value type of time.Time for the given layout,
+// which defaults to RFC 3339
+func timeValueType(layout string) *valueType {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return &valueType{
+		name: "time",
+		parse: func(text string) (interface{}, error) {
+			return time.Parse(layout, text)
+		},
+		format: func(value interface{}) string {
+			return value.(time.Time).Format(layout)
+		},
+	}
+}
+
+// findValueType returns the value type for the given type, or for the
+// elements of the given slice type, or nil if there isn't one
+func findValueType(typ reflect.Type, layout string) *valueType {
+	if result := scalarValueType(typ, layout); result != nil {
+		return result
+	}
+	if typ.Kind() == reflect.Slice {
+		return scalarValueType(typ.Elem(), layout)
+	}
+	return nil
+}
+
+// scalarValueType returns the value type for the given type, or nil if
+// there isn't one
+func scalarValueType(typ reflect.Type, layout string) *valueType {
+	if typ == timeType {
+		return timeValueType(layout)
+	}
+	valueTypesLock.RLock()
+	result := valueTypes[typ]
+	valueTypesLock.RUnlock()
+	if result != nil {
+		return result
+	}
+	return customValueType(typ)
+}
+
+// customValueType returns the value type for a type that implements
+// flag.Value or encoding.TextUnmarshaler, either itself if it is a pointer
+// or through a pointer to it, or nil if it implements neither
+func customValueType(typ reflect.Type) *valueType {
+	implements := func(typ reflect.Type) bool {
+		return typ.Implements(flagValueType) || typ.Implements(textUnmarshalerType)
+	}
+	elem, pointer := typ, false
+	switch {
+	case typ.Kind() == reflect.Ptr && implements(typ):
+		elem, pointer = typ.Elem(), true
+	case typ.Kind() == reflect.Interface || !implements(reflect.PtrTo(typ)):
+		return nil
+	}
+	return &valueType{
+		name:   "value",
+		direct: !pointer && reflect.PtrTo(typ).Implements(flagValueType),
+		parse: func(text string) (interface{}, error) {
+			receiver := reflect.New(elem)
+			if err := setText(receiver.Interface(), text); err != nil {
+				return nil, err
+			}
+			if pointer {
+				return receiver.Interface(), nil
+			}
+			return receiver.Elem().Interface(), nil
+		},
+		format: func(value interface{}) string {
+			receiver := reflect.ValueOf(value)
+			if !pointer {
+				receiver = reflect.New(elem)
+				receiver.Elem().Set(reflect.ValueOf(value))
+			}
+			return formatText(receiver.Interface())
+		},
+	}
+}
+
+// setText sets the value, which implements flag.Value or
+// encoding.TextUnmarshaler, from the given text
+func setText(value interface{}, text string) error {
+	if setter, ok := value.(flag.Value); ok {
+		return setter.Set(text)
+	}
+	return value.(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+}
+
+// formatText returns the value as text using its String or MarshalText
+// method if it has one
+func formatText(value interface{}) string {
+	switch formatter := value.(type) {
+	case fmt.Stringer:
+		return formatter.String()
+	case encoding.TextMarshaler:
+		if text, err := formatter.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(reflect.Indirect(reflect.ValueOf(value)).Interface())
+}
+
+// typedValue is a flag.Value for a variable of a value type, or a slice of
+// them, which is appended to each time the flag is given
+type typedValue struct {
+	target reflect.Value
+	kind   *valueType
+	slice  bool
+}
+
+// newTypedValue returns a flag.Value that sets the given addressable
+// variable
+func newTypedValue(target reflect.Value, kind *valueType) *typedValue {
+	slice := target.Kind() == reflect.Slice && scalarValueType(target.Type(), "") == nil
+	return &typedValue{target: target, kind: kind, slice: slice}
+}
+
+// Set - Parses the value and sets the variable, or appends it to a slice
+func (v *typedValue) Set(text string) error {
+	value, err := v.kind.parse(text)
+	if err != nil {
+		return err
+	}
+	if v.slice {
+		v.target.Set(reflect.Append(v.target, reflect.ValueOf(value)))
+		return nil
+	}
+	v.target.Set(reflect.ValueOf(value))
+	return nil
+}
+
+// String - Returns the formatted value, or blank if it is the zero value
+func (v *typedValue) String() string {
+	if v == nil || !v.target.IsValid() || v.target.IsZero() {
+		return ""
+	}
+	if !v.slice {
+		return v.kind.format(v.target.Interface())
+	}
+	values := make([]string, v.target.Len())
+	for index := range values {
+		values[index] = v.kind.format(v.target.Index(index).Interface())
+	}
+	return "[" + strings.Join(values, " ") + "]"
+}
+
+func (v *typedValue) variable() reflect.Value {
+	return v.target
+}
+
+func (v *typedValue) typeName() string {
+	return v.kind.name
+}
+
+// setAll sets the variable to the given text, which replaces the values
+// of a slice after being split using the separator. It is used for default
+// tags and positional arguments.
+func (v *typedValue) setAll(text, separator string) error {
+	values := []string{text}
+	if v.slice {
+		v.target.Set(reflect.Zero(v.target.Type()))
+		if separator != "" {
+			values = strings.Split(text, separator)
+		}
+	}
+	for _, value := range values {
+		if err := v.Set(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addValueField adds a flag for a struct field of a value type. Fields
+// that implement flag.Value through a pointer are used as the flag's value
+// directly, so that they may accumulate the values they are given.
+func (c *Command) addValueField(name, description, defaultValue, separator, pos string, field reflect.Value, kind *valueType) {
+	var value flag.Value = newTypedValue(field, kind)
+	if direct, ok := field.Addr().Interface().(flag.Value); ok && kind.direct {
+		value = direct
+	}
+	if defaultValue != "" {
+		if err := setPositional(value, defaultValue, separator); err != nil {
+			panic("Invalid default value for " + kind.name + " flag")
+		}
+	}
+	c.flags.Var(value, name, description)
+	c.flagCount++
+	if pos != "" {
+		c.positionalDetails[pos].value = value
+	}
+}
+
+// setPositional sets the value of a positional argument, or of a default
+// tag, which is split using the separator for slices of a value type
+func setPositional(value flag.Value, text, separator string) error {
+	if typed, ok := value.(*typedValue); ok {
+		return typed.setAll(text, separator)
+	}
+	return value.Set(text)
+}
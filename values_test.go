@@ -0,0 +1,206 @@
+package clir
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type networkFlags struct {
+	Timeout  time.Duration   `name:"timeout" description:"The timeout" default:"5s"`
+	Since    time.Time       `name:"since" description:"Show changes since" layout:"2006-01-02"`
+	Endpoint *url.URL        `name:"endpoint" description:"The endpoint"`
+	Address  net.IP          `name:"address" description:"The address" default:"127.0.0.1"`
+	Network  net.IPNet       `name:"network" description:"The network"`
+	Filter   *regexp.Regexp  `name:"filter" description:"The filter"`
+	Serial   *big.Int        `name:"serial" description:"The serial number"`
+	Mode     os.FileMode     `name:"mode" description:"The file mode" default:"0644"`
+	Retries  []time.Duration `name:"retry" description:"The retry delays" default:"1s,2s" sep:","`
+	Peers    []net.IP        `name:"peer" description:"The peers"`
+	Host     net.IP          `pos:"1" name:"host" description:"The host"`
+	Windows  []time.Time     `pos:"2" name:"windows" description:"The windows" layout:"15:04" sep:","`
+}
+
+func TestCommand_ValueTypes(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	flags := &networkFlags{}
+	c.AddFlags(flags)
+
+	if flags.Timeout != 5*time.Second || !flags.Address.Equal(net.ParseIP("127.0.0.1")) || flags.Mode != 0644 {
+		t.Errorf("unexpected defaults: %+v", flags)
+	}
+	if !reflect.DeepEqual(flags.Retries, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("unexpected default retries: %v", flags.Retries)
+	}
+
+	e := c.Run("--timeout", "1m", "--since", "2024-02-01", "--endpoint", "https://example.com/api",
+		"--network", "10.0.0.0/8", "--filter", "^web-", "--serial", "0x1f", "--mode", "0755",
+		"--retry", "5s", "--peer", "10.0.0.1", "--peer", "::1", "192.168.1.1", "09:00,17:30")
+	if e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Timeout != time.Minute || flags.Since != time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("unexpected times: %v, %v", flags.Timeout, flags.Since)
+	}
+	if flags.Endpoint.Host != "example.com" || flags.Network.String() != "10.0.0.0/8" || !flags.Filter.MatchString("web-1") {
+		t.Errorf("unexpected values: %v, %v, %v", flags.Endpoint, flags.Network, flags.Filter)
+	}
+	if flags.Serial.Int64() != 31 || flags.Mode != 0755 {
+		t.Errorf("unexpected values: %v, %v", flags.Serial, flags.Mode)
+	}
+	if !reflect.DeepEqual(flags.Retries, []time.Duration{5 * time.Second}) || len(flags.Peers) != 2 {
+		t.Errorf("unexpected slices: %v, %v", flags.Retries, flags.Peers)
+	}
+	if !flags.Host.Equal(net.ParseIP("192.168.1.1")) || len(flags.Windows) != 2 || flags.Windows[1].Hour() != 17 {
+		t.Errorf("unexpected positional arguments: %v, %v", flags.Host, flags.Windows)
+	}
+
+	// Values are restored by ResetFlags
+	c.ResetFlags()
+	if flags.Timeout != 5*time.Second || flags.Endpoint != nil || len(flags.Peers) != 0 || len(flags.Retries) != 2 {
+		t.Errorf("expected values to be reset, got %+v", flags)
+	}
+
+	tests := []struct {
+		args  []string
+		flag  string
+		arg   string
+		value string
+	}{
+		{[]string{"--timeout", "soon"}, "timeout", "", "soon"},
+		{[]string{"--since", "01/02/2024"}, "since", "", "01/02/2024"},
+		{[]string{"--address", "localhost"}, "address", "", "localhost"},
+		{[]string{"--network", "10.0.0.0"}, "network", "", "10.0.0.0"},
+		{[]string{"--filter", "("}, "filter", "", "("},
+		{[]string{"--serial", "abc"}, "serial", "", "abc"},
+		{[]string{"--mode", "0999"}, "mode", "", "0999"},
+		{[]string{"host"}, "", "host", "host"},
+	}
+	for _, test := range tests {
+		c.ResetFlags()
+		e := c.Run(test.args...)
+		var invalidValue *InvalidValueError
+		if !errors.As(e, &invalidValue) || invalidValue.Flag != test.flag || invalidValue.Arg != test.arg || invalidValue.Value != test.value {
+			t.Errorf("%v: expected an invalid value error, got %v", test.args, e)
+		}
+	}
+}
+
+func TestCommand_ValueTypesHelp(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	c.AddFlags(&networkFlags{})
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if e := c.Run("--help"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	for _, expected := range []string{
+		"  --timeout duration\n    \tThe timeout (default 5s)\n",
+		"  --since time\n    \tShow changes since\n",
+		"  --address ip\n    \tThe address (default 127.0.0.1)\n",
+		"  --mode mode\n    \tThe file mode (default 0644)\n",
+		"  --retry duration\n    \tThe retry delays (default [1s 2s])\n",
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected help to contain %q, got:\n%s", expected, output.String())
+		}
+	}
+}
+
+// region implements flag.Value
+type region string
+
+func (r *region) String() string { return string(*r) }
+
+func (r *region) Set(value string) error {
+	if !strings.Contains(value, "-") {
+		return errors.New("regions are of the form 'eu-west-1'")
+	}
+	*r = region(value)
+	return nil
+}
+
+// semVer implements encoding.TextUnmarshaler and encoding.TextMarshaler
+type semVer struct {
+	Major, Minor, Patch int
+}
+
+func (v *semVer) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "v%d.%d.%d", &v.Major, &v.Minor, &v.Patch)
+	return err
+}
+
+func (v semVer) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)), nil
+}
+
+// labels implements flag.Value and accumulates the values it is given
+type labels []string
+
+func (l *labels) String() string { return strings.Join(*l, ",") }
+
+func (l *labels) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+type releaseFlags struct {
+	Region   region   `name:"region" description:"The region" default:"eu-west-1"`
+	Version  semVer   `pos:"1" name:"version" description:"The version"`
+	Previous *semVer  `name:"previous" description:"The previous version"`
+	Extra    []semVer `name:"extra" description:"Extra versions"`
+	Labels   labels   `name:"label" description:"The labels"`
+}
+
+func TestCommand_CustomValueTypes(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	flags := &releaseFlags{}
+	var ran bool
+	c.NewSubCommandFunction("release", "Release a version", func(f *releaseFlags) error {
+		flags = f
+		ran = true
+		return nil
+	})
+
+	e := c.Run("release", "--region", "us-east-1", "--previous", "v1.1.0", "--extra", "v0.9.0", "--extra", "v0.8.0",
+		"--label", "a", "--label", "b", "v1.2.3")
+	if e != nil || !ran {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	if flags.Region != "us-east-1" || flags.Version != (semVer{1, 2, 3}) || *flags.Previous != (semVer{1, 1, 0}) {
+		t.Errorf("unexpected values: %+v", flags)
+	}
+	if !reflect.DeepEqual(flags.Extra, []semVer{{0, 9, 0}, {0, 8, 0}}) || !reflect.DeepEqual(flags.Labels, labels{"a", "b"}) {
+		t.Errorf("unexpected slices: %v, %v", flags.Extra, flags.Labels)
+	}
+
+	c.ResetFlags()
+	if e := c.Run("release", "--region", "nowhere"); e == nil || !strings.Contains(e.Error(), "regions are of the form") {
+		t.Errorf("expected an invalid region error, got %v", e)
+	}
+	c.ResetFlags()
+	var invalidValue *InvalidValueError
+	if e := c.Run("release", "1.2"); !errors.As(e, &invalidValue) || invalidValue.Arg != "version" {
+		t.Errorf("expected an invalid version error, got %v", e)
+	}
+
+	var output bytes.Buffer
+	c.SetOutput(&output)
+	if e := c.Run("release", "--help"); e != nil {
+		t.Fatalf("expected no error, got %v", e)
+	}
+	expected := "  --region value\n    \tThe region (default \"eu-west-1\")\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected help to contain %q, got:\n%s", expected, output.String())
+	}
+}
@@ -0,0 +1,31 @@
+//go:build go1.21
+
+package clir
+
+import (
+	"log/slog"
+)
+
+// VerbosityFlag - Adds a persistent `-v, --verbose` flag that may be
+// repeated to lower the given log level, EG: `-v` lowers slog.LevelInfo to
+// slog.LevelDebug. The level is set before the command's action is run,
+// starting from its value when this method is called, EG:
+//
+//	var level slog.LevelVar
+//	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &level}))
+//	cli.VerbosityFlag(&level)
+//
+// It requires Go 1.21 or later.
+func (c *Cli) VerbosityFlag(level *slog.LevelVar) *Cli {
+	base := level.Level()
+	c.verbosity = &verbosity{
+		apply: func(count int) {
+			// Each step is the gap between the slog levels
+			level.Set(base - slog.Level(count)*(slog.LevelInfo-slog.LevelDebug))
+		},
+	}
+	c.PersistentFlags().
+		CountFlag("verbose", "Increase verbosity, may be repeated", &c.verbosity.count).
+		ShortFlag("verbose", "v")
+	return c
+}
@@ -0,0 +1,37 @@
+//go:build go1.21
+
+package clir
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCli_VerbosityFlag(t *testing.T) {
+	c := NewCli("app", "description", "0")
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	var actionLevel slog.Level
+	c.VerbosityFlag(&level)
+	c.NewSubCommand("deploy", "Deploy the app").Action(func() error {
+		actionLevel = level.Level()
+		return nil
+	})
+
+	tests := map[string]slog.Level{
+		"deploy":          slog.LevelWarn,
+		"deploy -v":       slog.LevelInfo,
+		"deploy -vv":      slog.LevelDebug,
+		"-v deploy -v -v": slog.LevelDebug - 4,
+	}
+	for args, expected := range tests {
+		c.ResetFlags()
+		if e := c.Run(strings.Fields(args)...); e != nil {
+			t.Fatalf("%s: expected no error, got %v", args, e)
+		}
+		if actionLevel != expected {
+			t.Errorf("%s: expected level %v, got %v", args, expected, actionLevel)
+		}
+	}
+}